@@ -0,0 +1,660 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/firadio/jinja-hub/hub"
+	"github.com/redis/go-redis/v9"
+)
+
+// serverConfig 服务器配置
+// 优先级：命令行参数 > 配置文件 > 默认值
+type serverConfig struct {
+	Addr            string             `json:"addr"`
+	SitesDir        string             `json:"sites_dir"`
+	CDNCacheDir     string             `json:"cdn_cache_dir"` // 为空时使用 {sites_dir}/_static/cdn
+	DataDir         string             `json:"data_dir"`      // 运行时数据（封禁记录、审计日志、资产快照）的默认目录，站点目录保持只读
+	AdminToken      string             `json:"admin_token"`
+	CredentialsFile string             `json:"credentials_file"` // 签名代理使用的云服务凭证文件，为空时只读取环境变量
+	APIUsers        []apiUserConfig    `json:"api_users"`        // 可以使用服务端凭证接口的用户
+	SessionTTL      duration           `json:"session_ttl"`      // 浏览器登录会话的有效期
+	TrustedProxies  []string           `json:"trusted_proxies"`  // 受信任的反向代理 IP 或 CIDR
+	ProxyProtocol   bool               `json:"proxy_protocol"`   // 监听端口接受 PROXY protocol v1/v2
+	Watch           duration           `json:"watch"`
+	Precompress     bool               `json:"precompress"` // 启动时生成 .br/.zst/.gz 预压缩文件
+	EgressRate      int64              `json:"egress_rate"` // 所有响应合计的出口带宽上限（字节/秒），0 表示不限制
+	MaxBodyBytes    int64              `json:"max_body_bytes"`
+	MaxHeaderBytes  int                `json:"max_header_bytes"`
+	RateLimit       rateLimitConfig    `json:"rate_limit"`
+	Ban             banConfig          `json:"ban"`
+	Vault           vaultConfig        `json:"vault"`
+	Concurrency     concurrencyConfig  `json:"concurrency"`
+	FanOut          fanOutConfig       `json:"fanout"`
+	Policy          policyConfig       `json:"policy"`
+	AuditLog        string             `json:"audit_log"` // 资源管理操作的审计日志文件，为空时放在 data_dir 中
+	Inventory       inventoryConfig    `json:"inventory"`
+	StatusStream    statusStreamConfig `json:"status_stream"`
+	Timeouts        timeoutConfig      `json:"timeouts"`
+}
+
+// rateLimitConfig 速率限制配置
+// 规则按 站点+路由类别 > 站点 > 路由类别 > 默认 的顺序选择，路由类别为 page、static、cdn、api、admin
+type rateLimitConfig struct {
+	rateLimitRule
+	MaxEntries  int                            `json:"max_entries"`  // 最多跟踪的计数数量（LRU 淘汰）
+	TrafficMode string                         `json:"traffic_mode"` // 流量超出 max_bytes 后：reject 返回 429，shape 限速到 shape_rate
+	ShapeRate   int64                          `json:"shape_rate"`   // 整形模式下每个 IP 的限速（字节/秒）
+	RedisURL    string                         `json:"redis_url"`    // 多实例共享计数的 Redis 地址，例如 redis://localhost:6379/0，为空时使用内存
+	RedisPrefix string                         `json:"redis_prefix"` // Redis 键前缀，默认 jinja-hub:ratelimit:
+	Routes      map[string]rateLimitRule       `json:"routes"`
+	Sites       map[string]siteRateLimitConfig `json:"sites"`
+}
+
+// newLimiter 创建速率限制器，配置了 redis_url 时计数保存在 Redis 中
+func (c rateLimitConfig) newLimiter() (*hub.RateLimiter, error) {
+	if c.RedisURL == "" {
+		return hub.NewRateLimiter(c.toHub(), nil), nil
+	}
+	options, err := redis.ParseURL(c.RedisURL)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(options)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		// 不阻止启动：Redis 不可用期间请求会被放行
+		log.Printf("[RateLimit] Redis %s is not reachable: %v", options.Addr, err)
+	}
+	return hub.NewRateLimiterWithStore(c.toHub(), hub.NewRedisStore(client, c.RedisPrefix), nil), nil
+}
+
+// banConfig 自动封禁配置，threshold 为 0 时不启用
+type banConfig struct {
+	Threshold   int      `json:"threshold"`    // 计分窗口内达到该分数时封禁（404 计 1 分，403/400 计 2 分，路径遍历计 10 分）
+	Window      duration `json:"window"`       // 计分窗口
+	Duration    duration `json:"duration"`     // 第一次封禁的时长，之后每次翻倍
+	MaxDuration duration `json:"max_duration"` // 封禁时长上限
+	File        string   `json:"file"`         // 封禁列表的持久化文件，为空时使用 {data_dir}/bans.json
+}
+
+// toHub 转换为 hub 包的封禁配置
+func (c banConfig) toHub() hub.BanConfig {
+	return hub.BanConfig{
+		Threshold:   c.Threshold,
+		Window:      time.Duration(c.Window),
+		Duration:    time.Duration(c.Duration),
+		MaxDuration: time.Duration(c.MaxDuration),
+		File:        c.File,
+	}
+}
+
+// vaultConfig 加密保存凭证的保险库，file 为空时不启用
+// 口令从环境变量 JINJA_HUB_VAULT_PASSPHRASE 读取，配置了 key_file 时使用密钥文件
+type vaultConfig struct {
+	File    string            `json:"file"`
+	KeyFile string            `json:"key_file"`
+	Sites   map[string]string `json:"sites"` // 站点名（"*" 为所有站点）到保险库中的凭证名称
+}
+
+// key 返回打开保险库的密钥来源
+func (c vaultConfig) key() (hub.VaultKey, error) {
+	if c.KeyFile != "" {
+		return hub.VaultKey{KeyFile: c.KeyFile}, nil
+	}
+	if passphrase := os.Getenv("JINJA_HUB_VAULT_PASSPHRASE"); passphrase != "" {
+		return hub.VaultKey{Passphrase: passphrase}, nil
+	}
+	return hub.VaultKey{}, fmt.Errorf("vault requires key_file or the JINJA_HUB_VAULT_PASSPHRASE environment variable")
+}
+
+// apiUserConfig 可以使用签名代理等服务端凭证接口的用户，令牌只保存 SHA-256
+type apiUserConfig struct {
+	Name        string   `json:"name"`
+	TokenSHA256 string   `json:"token_sha256"` // 访问令牌的 SHA-256（十六进制），例如 printf %s "$TOKEN" | sha256sum
+	Sites       []string `json:"sites"`        // 允许访问的站点，为空表示所有站点
+}
+
+// apiUsers 转换为 hub 包的用户列表
+func (c serverConfig) apiUsers() []hub.APIUser {
+	users := make([]hub.APIUser, 0, len(c.APIUsers))
+	for _, user := range c.APIUsers {
+		users = append(users, hub.APIUser{Name: user.Name, TokenSHA256: user.TokenSHA256, Sites: user.Sites})
+	}
+	return users
+}
+
+// concurrencyConfig 并发限制，max_in_flight 和 max_in_flight_per_site 都为 0 时不启用
+type concurrencyConfig struct {
+	MaxInFlight        int      `json:"max_in_flight"`          // 全局同时处理的请求数
+	MaxInFlightPerSite int      `json:"max_in_flight_per_site"` // 每个站点同时处理的请求数
+	MaxQueue           int      `json:"max_queue"`              // 每个通道最多等待的请求数
+	QueueTimeout       duration `json:"queue_timeout"`          // 最长等待时间
+	PriorityInFlight   int      `json:"priority_in_flight"`     // 健康检查和管理接口的并发数
+	StreamInFlight     int      `json:"stream_in_flight"`       // 状态推送、限速写出的响应（以及配置了 egress_rate 时的静态文件、CDN 文件和导出）的并发数
+}
+
+// toHub 转换为 hub 包的并发限制配置
+func (c concurrencyConfig) toHub() hub.ConcurrencyConfig {
+	return hub.ConcurrencyConfig{
+		MaxInFlight:        c.MaxInFlight,
+		MaxInFlightPerSite: c.MaxInFlightPerSite,
+		MaxQueue:           c.MaxQueue,
+		QueueTimeout:       time.Duration(c.QueueTimeout),
+		PriorityInFlight:   c.PriorityInFlight,
+		StreamInFlight:     c.StreamInFlight,
+	}
+}
+
+// fanOutConfig 跨地域聚合查询的限制
+type fanOutConfig struct {
+	Concurrency   int      `json:"concurrency"`    // 同时查询的地域数
+	RegionTimeout duration `json:"region_timeout"` // 每个地域（包括所有分页）的超时时间
+	MaxPages      int      `json:"max_pages"`      // 每个地域最多读取的页数
+}
+
+// toHub 转换为 hub 包的聚合查询配置
+func (c fanOutConfig) toHub() hub.FanOutConfig {
+	return hub.FanOutConfig{
+		Concurrency:   c.Concurrency,
+		RegionTimeout: time.Duration(c.RegionTimeout),
+		MaxPages:      c.MaxPages,
+	}
+}
+
+// policyConfig 资源管理操作（/{site}/api/manage/）和签名代理中非只读接口的服务端策略，规则按顺序匹配
+type policyConfig struct {
+	Default    string             `json:"default"`     // 没有规则匹配时的效果：allow、deny 或 confirm
+	ConfirmTTL duration           `json:"confirm_ttl"` // 确认令牌的有效期
+	Rules      []policyRuleConfig `json:"rules"`
+}
+
+// policyRuleConfig 一条策略规则，site、user、credential、resource、action、api 为空时匹配所有，支持 * 通配符
+type policyRuleConfig struct {
+	Site       string `json:"site"`
+	User       string `json:"user"`       // api_users 中的用户名
+	Credential string `json:"credential"` // 保险库中的凭证名称，或凭证文件中的站点键
+	Resource   string `json:"resource"`
+	Action     string `json:"action"`
+	API        string `json:"api"` // 调用的云服务 API，例如 vpc/ReleaseEipAddress 或 ecs/Delete*
+	Effect     string `json:"effect"`
+}
+
+// toHub 转换为 hub 包的策略
+func (c policyConfig) toHub() hub.Policy {
+	policy := hub.Policy{Default: c.Default, ConfirmTTL: time.Duration(c.ConfirmTTL)}
+	for _, rule := range c.Rules {
+		policy.Rules = append(policy.Rules, hub.PolicyRule{
+			Site:       rule.Site,
+			User:       rule.User,
+			Credential: rule.Credential,
+			Resource:   rule.Resource,
+			Action:     rule.Action,
+			API:        rule.API,
+			Effect:     rule.Effect,
+		})
+	}
+	return policy
+}
+
+// inventoryConfig 资产快照，定期为表格执行跨地域聚合查询并保存到磁盘
+type inventoryConfig struct {
+	Dir      string              `json:"dir"`      // 快照目录，为空时使用 {data_dir}/inventory
+	Interval duration            `json:"interval"` // 快照间隔，0 表示不定期快照（仍可通过 /_admin/inventory 手动触发）
+	Retain   int                 `json:"retain"`   // 每个表格保留的快照数，0 表示全部保留
+	Tables   map[string][]string `json:"tables"`   // 站点名（"*" 为所有站点）到表格列表，为空时为所有配置了 rowKey 的表格
+}
+
+// toHub 转换为 hub 包的资产快照配置
+func (c inventoryConfig) toHub() hub.InventoryConfig {
+	return hub.InventoryConfig{
+		Dir:      c.Dir,
+		Interval: time.Duration(c.Interval),
+		Retain:   c.Retain,
+		Tables:   c.Tables,
+	}
+}
+
+// statusStreamConfig 资源状态推送（/{site}/api/status/{table}）的轮询和连接限制
+type statusStreamConfig struct {
+	Interval duration `json:"interval"` // 轮询间隔，同一地域的所有连接共享
+	Settle   duration `json:"settle"`   // 资源一直处于最终状态时，经过多久视为已稳定
+	Timeout  duration `json:"timeout"`  // 每个连接的最长时间
+	MaxIDs   int      `json:"max_ids"`  // 每个连接最多关注的资源数
+}
+
+// toHub 转换为 hub 包的状态推送配置
+func (c statusStreamConfig) toHub() hub.StatusStreamConfig {
+	return hub.StatusStreamConfig{
+		Interval: time.Duration(c.Interval),
+		Settle:   time.Duration(c.Settle),
+		Timeout:  time.Duration(c.Timeout),
+		MaxIDs:   c.MaxIDs,
+	}
+}
+
+// rateLimitRule 一组限制，max_requests / max_bytes 为 0 表示不限制
+type rateLimitRule struct {
+	Window      duration `json:"window"`
+	MaxRequests int      `json:"max_requests"`
+	Burst       int      `json:"burst"`     // 令牌桶容量，默认等于 max_requests
+	MaxBytes    int64    `json:"max_bytes"` // 每个窗口内最大字节数
+}
+
+// siteRateLimitConfig 单个站点的速率限制
+type siteRateLimitConfig struct {
+	rateLimitRule
+	Routes map[string]rateLimitRule `json:"routes"`
+}
+
+// toHub 转换为 hub 包的速率限制配置
+func (c rateLimitConfig) toHub() hub.RateLimitConfig {
+	config := hub.RateLimitConfig{
+		RateLimitRule: c.rateLimitRule.toHub(),
+		Routes:        rulesToHub(c.Routes),
+		MaxEntries:    c.MaxEntries,
+		TrafficMode:   c.TrafficMode,
+		ShapeRate:     c.ShapeRate,
+	}
+	if len(c.Sites) > 0 {
+		config.Sites = make(map[string]hub.SiteRateLimitConfig, len(c.Sites))
+		for site, siteConfig := range c.Sites {
+			config.Sites[site] = hub.SiteRateLimitConfig{
+				RateLimitRule: siteConfig.rateLimitRule.toHub(),
+				Routes:        rulesToHub(siteConfig.Routes),
+			}
+		}
+	}
+	return config
+}
+
+func (r rateLimitRule) toHub() hub.RateLimitRule {
+	return hub.RateLimitRule{
+		Window:      time.Duration(r.Window),
+		MaxRequests: r.MaxRequests,
+		Burst:       r.Burst,
+		MaxBytes:    r.MaxBytes,
+	}
+}
+
+func rulesToHub(rules map[string]rateLimitRule) map[string]hub.RateLimitRule {
+	if len(rules) == 0 {
+		return nil
+	}
+	converted := make(map[string]hub.RateLimitRule, len(rules))
+	for route, rule := range rules {
+		converted[route] = rule.toHub()
+	}
+	return converted
+}
+
+// timeoutConfig HTTP 服务器超时配置
+type timeoutConfig struct {
+	Read       duration `json:"read"`
+	ReadHeader duration `json:"read_header"`
+	Write      duration `json:"write"`
+	Idle       duration `json:"idle"`
+}
+
+// duration 支持在 JSON 中使用 "60s"、"2m" 这样的写法
+type duration time.Duration
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"60s\": %s", data)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(parsed)
+	return nil
+}
+
+// defaultSitesDir 默认站点目录：可执行文件所在目录的 ../../sites（在仓库的 servers/go 中编译的二进制），
+// 该目录不存在时（例如 go run 生成的临时文件）使用工作目录的 ../../sites
+func defaultSitesDir() string {
+	rel := filepath.Join("..", "..", "sites")
+	exeDir, ok := executableDir()
+	if !ok {
+		return rel
+	}
+	dir := filepath.Join(exeDir, rel)
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		return dir
+	}
+	return rel
+}
+
+// defaultDataDir 默认数据目录：可执行文件所在目录的 data，不受工作目录影响（systemd 下工作目录为 /）；
+// go run 生成的临时文件会被清理，此时使用工作目录的 data
+func defaultDataDir() string {
+	exeDir, ok := executableDir()
+	if !ok || strings.Contains(exeDir, "go-build") {
+		if abs, err := filepath.Abs("data"); err == nil {
+			return abs
+		}
+		return "data"
+	}
+	return filepath.Join(exeDir, "data")
+}
+
+// executableDir 可执行文件（解析符号链接后）所在的目录
+func executableDir() (string, bool) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", false
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	return filepath.Dir(exe), true
+}
+
+func defaultServerConfig() serverConfig {
+	return serverConfig{
+		Addr:           ":8080",
+		SitesDir:       defaultSitesDir(),
+		DataDir:        defaultDataDir(),
+		AdminToken:     os.Getenv("JINJA_HUB_ADMIN_TOKEN"),
+		SessionTTL:     duration(12 * time.Hour),
+		Watch:          duration(2 * time.Second),
+		MaxBodyBytes:   10 << 20, // 10 MB
+		MaxHeaderBytes: 1 << 20,  // 1 MB
+		RateLimit: rateLimitConfig{
+			rateLimitRule: rateLimitRule{
+				Window:      duration(hub.DefaultRateLimitConfig.Window),
+				MaxRequests: hub.DefaultRateLimitConfig.MaxRequests,
+				MaxBytes:    hub.DefaultRateLimitConfig.MaxBytes,
+			},
+		},
+		Ban: banConfig{
+			Threshold:   0,
+			Window:      duration(time.Minute),
+			Duration:    duration(10 * time.Minute),
+			MaxDuration: duration(24 * time.Hour),
+		},
+		Concurrency: concurrencyConfig{
+			MaxInFlight:      256,
+			MaxQueue:         512,
+			QueueTimeout:     duration(time.Second),
+			PriorityInFlight: 8,
+			StreamInFlight:   64,
+		},
+		FanOut: fanOutConfig{
+			Concurrency:   8,
+			RegionTimeout: duration(20 * time.Second),
+			MaxPages:      20,
+		},
+		Policy: policyConfig{
+			Default:    hub.PolicyDeny,
+			ConfirmTTL: duration(2 * time.Minute),
+		},
+		Inventory: inventoryConfig{
+			Retain: 90,
+		},
+		StatusStream: statusStreamConfig{
+			Interval: duration(3 * time.Second),
+			Settle:   duration(15 * time.Second),
+			Timeout:  duration(10 * time.Minute),
+			MaxIDs:   50,
+		},
+		Timeouts: timeoutConfig{
+			Read:       duration(60 * time.Second),
+			ReadHeader: duration(60 * time.Second),
+			Write:      duration(120 * time.Second),
+			Idle:       duration(120 * time.Second),
+		},
+	}
+}
+
+// loadServerConfig 从 JSON 文件加载服务器配置，未出现的字段保留默认值
+// 配置文件中的相对路径以配置文件所在目录为基准，便于从任意工作目录启动
+func loadServerConfig(path string, base serverConfig) (serverConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return base, err
+	}
+
+	config := base
+	config.SitesDir = ""
+	config.CDNCacheDir = ""
+	config.DataDir = ""
+	config.Ban.File = ""
+	config.AuditLog = ""
+	config.Inventory.Dir = ""
+	config.CredentialsFile = ""
+	config.Vault.File = ""
+	config.Vault.KeyFile = ""
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return base, fmt.Errorf("%s: %w", path, err)
+	}
+
+	configDir := filepath.Dir(path)
+	if config.SitesDir == "" {
+		config.SitesDir = base.SitesDir
+	} else if !filepath.IsAbs(config.SitesDir) {
+		config.SitesDir = filepath.Join(configDir, config.SitesDir)
+	}
+	if config.CDNCacheDir == "" {
+		config.CDNCacheDir = base.CDNCacheDir
+	} else if !filepath.IsAbs(config.CDNCacheDir) {
+		config.CDNCacheDir = filepath.Join(configDir, config.CDNCacheDir)
+	}
+	if config.DataDir == "" {
+		config.DataDir = base.DataDir
+	} else if !filepath.IsAbs(config.DataDir) {
+		config.DataDir = filepath.Join(configDir, config.DataDir)
+	}
+
+	if config.Ban.File == "" {
+		config.Ban.File = base.Ban.File
+	} else if !filepath.IsAbs(config.Ban.File) {
+		config.Ban.File = filepath.Join(configDir, config.Ban.File)
+	}
+	if config.AuditLog == "" {
+		config.AuditLog = base.AuditLog
+	} else if !filepath.IsAbs(config.AuditLog) {
+		config.AuditLog = filepath.Join(configDir, config.AuditLog)
+	}
+	if config.Inventory.Dir == "" {
+		config.Inventory.Dir = base.Inventory.Dir
+	} else if !filepath.IsAbs(config.Inventory.Dir) {
+		config.Inventory.Dir = filepath.Join(configDir, config.Inventory.Dir)
+	}
+	if config.CredentialsFile == "" {
+		config.CredentialsFile = base.CredentialsFile
+	} else if !filepath.IsAbs(config.CredentialsFile) {
+		config.CredentialsFile = filepath.Join(configDir, config.CredentialsFile)
+	}
+	if config.Vault.File == "" {
+		config.Vault.File = base.Vault.File
+	} else if !filepath.IsAbs(config.Vault.File) {
+		config.Vault.File = filepath.Join(configDir, config.Vault.File)
+	}
+	if config.Vault.KeyFile == "" {
+		config.Vault.KeyFile = base.Vault.KeyFile
+	} else if !filepath.IsAbs(config.Vault.KeyFile) {
+		config.Vault.KeyFile = filepath.Join(configDir, config.Vault.KeyFile)
+	}
+
+	return config, nil
+}
+
+// validate 检查配置是否可用
+// 内嵌站点时磁盘上的站点目录只用于覆盖，可以不存在
+func (c serverConfig) validate() error {
+	info, err := os.Stat(c.SitesDir)
+	if err != nil && embeddedSites == nil {
+		return fmt.Errorf("sites_dir: %w", err)
+	}
+	if err == nil && !info.IsDir() {
+		return fmt.Errorf("sites_dir: %s is not a directory", c.SitesDir)
+	}
+	if _, err := hub.ParseTrustedProxies(c.TrustedProxies); err != nil {
+		return fmt.Errorf("trusted_proxies: %w", err)
+	}
+	if c.ProxyProtocol && len(c.TrustedProxies) == 0 {
+		return fmt.Errorf("proxy_protocol requires trusted_proxies")
+	}
+	if err := c.RateLimit.toHub().Validate(); err != nil {
+		return fmt.Errorf("rate_limit: %w", err)
+	}
+	if c.RateLimit.RedisURL != "" {
+		if _, err := redis.ParseURL(c.RateLimit.RedisURL); err != nil {
+			return fmt.Errorf("rate_limit.redis_url: %w", err)
+		}
+	}
+	if c.EgressRate < 0 {
+		return fmt.Errorf("egress_rate must not be negative")
+	}
+	if err := c.Concurrency.toHub().Validate(); err != nil {
+		return fmt.Errorf("concurrency: %w", err)
+	}
+	if err := c.FanOut.toHub().Validate(); err != nil {
+		return fmt.Errorf("fanout: %w", err)
+	}
+	if err := c.Policy.toHub().Validate(); err != nil {
+		return fmt.Errorf("policy: %w", err)
+	}
+	if err := c.inventoryConfig().Validate(); err != nil {
+		return fmt.Errorf("inventory: %w", err)
+	}
+	if err := c.StatusStream.toHub().Validate(); err != nil {
+		return fmt.Errorf("status_stream: %w", err)
+	}
+	if err := c.Ban.toHub().Validate(); err != nil {
+		return fmt.Errorf("ban: %w", err)
+	}
+	names := make(map[string]bool, len(c.APIUsers))
+	for i, user := range c.apiUsers() {
+		if err := user.Validate(); err != nil {
+			return fmt.Errorf("api_users[%d]: %w", i, err)
+		}
+		if names[user.Name] {
+			return fmt.Errorf("api_users[%d]: duplicate name %s", i, user.Name)
+		}
+		names[user.Name] = true
+	}
+	if c.SessionTTL < 0 {
+		return fmt.Errorf("session_ttl must not be negative")
+	}
+	if c.Vault.File != "" && c.CredentialsFile != "" {
+		return fmt.Errorf("credentials_file and vault.file must not be used together")
+	}
+	if c.Vault.File != "" && len(c.Vault.Sites) == 0 {
+		return fmt.Errorf("vault.sites must map at least one site to a credential name")
+	}
+	if c.MaxBodyBytes <= 0 {
+		return fmt.Errorf("max_body_bytes must be positive")
+	}
+	return nil
+}
+
+// banConfig 返回自动封禁配置，持久化文件默认为 {data_dir}/bans.json
+func (c serverConfig) banConfig() hub.BanConfig {
+	config := c.Ban.toHub()
+	if config.File == "" {
+		config.File = filepath.Join(c.DataDir, "bans.json")
+	}
+	return config
+}
+
+// auditLogFile 返回审计日志文件，默认为 {data_dir}/audit.log
+func (c serverConfig) auditLogFile() string {
+	if c.AuditLog != "" {
+		return c.AuditLog
+	}
+	return filepath.Join(c.DataDir, "audit.log")
+}
+
+// inventoryConfig 返回资产快照配置，快照目录默认为 {data_dir}/inventory
+func (c serverConfig) inventoryConfig() hub.InventoryConfig {
+	config := c.Inventory.toHub()
+	if config.Dir == "" {
+		config.Dir = filepath.Join(c.DataDir, "inventory")
+	}
+	return config
+}
+
+// credentials 返回签名代理使用的凭证，没有配置任何凭证时返回 nil（不启用签名代理）
+// 配置了保险库时站点按名称引用保险库中的凭证；否则使用凭证文件，
+// 设置了 ALIBABA_CLOUD_ACCESS_KEY_ID 和 ALIBABA_CLOUD_ACCESS_KEY_SECRET 环境变量时，
+// 作为凭证文件中没有单独配置的站点的默认凭证
+func (c serverConfig) credentials() (hub.CredentialStore, error) {
+	if c.Vault.File != "" {
+		return c.Vault.open()
+	}
+
+	credentials := hub.StaticCredentials{}
+	if c.CredentialsFile != "" {
+		loaded, err := hub.LoadCredentialsFile(c.CredentialsFile)
+		if err != nil {
+			return nil, err
+		}
+		credentials = loaded
+	}
+	if _, ok := credentials["*"]; !ok {
+		id, secret := os.Getenv("ALIBABA_CLOUD_ACCESS_KEY_ID"), os.Getenv("ALIBABA_CLOUD_ACCESS_KEY_SECRET")
+		if id != "" && secret != "" {
+			credentials["*"] = hub.Credential{
+				AccessKeyID:     id,
+				AccessKeySecret: secret,
+				SecurityToken:   os.Getenv("ALIBABA_CLOUD_SECURITY_TOKEN"),
+			}
+		}
+	}
+	if len(credentials) == 0 {
+		return nil, nil
+	}
+	return credentials, nil
+}
+
+// cdnCacheDir 返回 CDN 缓存目录
+// 未配置且站点目录不存在（仅使用内嵌站点）时，使用系统缓存目录
+func (c serverConfig) cdnCacheDir() string {
+	if c.CDNCacheDir != "" {
+		return c.CDNCacheDir
+	}
+	if _, err := os.Stat(c.SitesDir); err != nil && embeddedSites != nil {
+		if cacheDir, err := os.UserCacheDir(); err == nil {
+			return filepath.Join(cacheDir, "jinja-hub", "cdn")
+		}
+		return filepath.Join(os.TempDir(), "jinja-hub", "cdn")
+	}
+	return filepath.Join(c.SitesDir, "_static", "cdn")
+}
+
+// open 打开保险库，站点引用的凭证必须存在
+func (c vaultConfig) open() (hub.CredentialStore, error) {
+	key, err := c.key()
+	if err != nil {
+		return nil, err
+	}
+	vault, err := hub.OpenVault(c.File, key)
+	if err != nil {
+		return nil, err
+	}
+	for site, name := range c.Sites {
+		if _, ok := vault.Lookup(name); !ok {
+			return nil, fmt.Errorf("vault.sites.%s: %w: %s", site, hub.ErrCredentialNotFound, name)
+		}
+	}
+	return hub.SiteCredentials{Source: vault, Sites: c.Sites}, nil
+}
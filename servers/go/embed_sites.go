@@ -0,0 +1,21 @@
+//go:build embed
+
+package main
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// 站点文件由 go generate 从 ../../sites 复制到 ./sites 后编译进二进制
+//
+//go:embed all:sites
+var embeddedFiles embed.FS
+
+func init() {
+	sub, err := fs.Sub(embeddedFiles, "sites")
+	if err != nil {
+		panic(err)
+	}
+	embeddedSites = sub
+}
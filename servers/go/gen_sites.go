@@ -0,0 +1,97 @@
+//go:build ignore
+
+// gen_sites 将站点目录复制到 ./sites，供 -tags embed 构建时编译进二进制
+// 只复制运行需要的文件：sites.json、各站点的 config.json、templates 和 static 目录（不含预压缩文件），
+// 封禁记录、审计日志、资产快照等运行时数据即使位于站点目录中也不会被内嵌
+//
+//	go generate                          # 不包含 CDN 缓存
+//	go run gen_sites.go -cdn             # 同时包含 _static/cdn 中已缓存的 CDN 文件
+package main
+
+import (
+	"flag"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	src := flag.String("src", filepath.Join("..", "..", "sites"), "源站点目录")
+	dst := flag.String("dst", "sites", "目标目录")
+	withCDN := flag.Bool("cdn", false, "包含 _static/cdn 中的 CDN 缓存")
+	flag.Parse()
+
+	if err := os.RemoveAll(*dst); err != nil {
+		log.Fatal(err)
+	}
+
+	err := filepath.WalkDir(*src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(*src, path)
+		if err != nil {
+			return err
+		}
+		if !included(filepath.ToSlash(rel), *withCDN) || isPrecompressed(path) {
+			return nil
+		}
+
+		target := filepath.Join(*dst, rel)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		return copyFile(path, target)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Copied %s to %s", *src, *dst)
+}
+
+// included 判断站点目录中的文件（rel 为以 / 分隔的相对路径）是否需要内嵌
+func included(rel string, withCDN bool) bool {
+	parts := strings.Split(rel, "/")
+	switch {
+	case rel == "sites.json":
+		return true
+	case parts[0] == "_static":
+		return withCDN && len(parts) > 2 && parts[1] == "cdn"
+	case len(parts) == 2:
+		return parts[1] == "config.json"
+	default:
+		return parts[1] == "templates" || parts[1] == "static"
+	}
+}
+
+// isPrecompressed 是否为 -precompress 生成的预压缩文件（原文件存在时的 .br/.zst/.gz）
+// 内嵌文件没有修改时间，无法判断预压缩文件是否过期，因此不内嵌，由服务器边读边压缩
+func isPrecompressed(path string) bool {
+	ext := filepath.Ext(path)
+	if ext != ".br" && ext != ".zst" && ext != ".gz" {
+		return false
+	}
+	_, err := os.Stat(strings.TrimSuffix(path, ext))
+	return err == nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
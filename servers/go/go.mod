@@ -1,7 +1,31 @@
 module github.com/firadio/jinja-hub
 
-go 1.21
+go 1.22
 
-require github.com/flosch/pongo2/v6 v6.0.0
+require (
+	github.com/alicebob/miniredis/v2 v2.39.0
+	github.com/andybalholm/brotli v1.2.0
+	github.com/flosch/pongo2/v6 v6.0.0
+	github.com/klauspost/compress v1.18.0
+	github.com/pires/go-proxyproto v0.7.0
+	github.com/redis/go-redis/v9 v9.14.1
+	github.com/xuri/excelize/v2 v2.8.1
+	golang.org/x/crypto v0.33.0
+	golang.org/x/term v0.29.0
+	golang.org/x/time v0.9.0
+)
 
-require github.com/kr/text v0.2.0 // indirect
+require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/mohae/deepcopy v0.0.0-20170929034955-c48cc78d4826 // indirect
+	github.com/richardlehane/mscfb v1.0.4 // indirect
+	github.com/richardlehane/msoleps v1.0.3 // indirect
+	github.com/xuri/efp v0.0.0-20231025114914-d1ff6096ae53 // indirect
+	github.com/xuri/nfp v0.0.0-20230919160717-d98342af3f05 // indirect
+	github.com/yuin/gopher-lua v1.1.1 // indirect
+	golang.org/x/net v0.21.0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
+	golang.org/x/text v0.22.0 // indirect
+)
@@ -0,0 +1,215 @@
+package hub
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// handleAdmin 处理 /_admin/ 下的管理接口
+func (s *Server) handleAdmin(w http.ResponseWriter, r *http.Request, action string) {
+	if !s.authorizeAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	switch action {
+	case "reload":
+		s.handleAdminReload(w, r)
+	case "bans":
+		s.handleAdminBans(w, r)
+	case "audit":
+		s.handleAdminAudit(w, r)
+	case "inventory":
+		s.handleAdminInventory(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// authorizeAdmin 校验管理接口访问权限
+func (s *Server) authorizeAdmin(r *http.Request) bool {
+	// 配置了令牌时必须携带令牌，否则仅允许本机访问
+	if s.adminToken != "" {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		return subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) == 1
+	}
+
+	// 没有令牌时只接受直接来自本机的请求：带转发头说明经过了反向代理，
+	// 即使代理在本机（且未配置为可信代理）也不能当作本机访问
+	for _, header := range []string{"Forwarded", "X-Forwarded-For", "X-Real-IP"} {
+		if r.Header.Get(header) != "" {
+			return false
+		}
+	}
+	ip := net.ParseIP(ClientIP(r))
+	return ip != nil && ip.IsLoopback()
+}
+
+// handleAdminReload 手动触发重新加载站点配置和模板
+func (s *Server) handleAdminReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.Reload(); err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "rejected",
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	state := s.getState()
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "ok",
+		"sites":     len(state.siteConfigs),
+		"loaded_at": state.loadedAt,
+	})
+}
+
+// handleAdminBans 查看（GET）和解除（DELETE）自动封禁
+// DELETE 带 ?ip= 时只解除该 IP，否则解除全部
+func (s *Server) handleAdminBans(w http.ResponseWriter, r *http.Request) {
+	if s.bans == nil {
+		http.Error(w, "Automatic banning is not enabled", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"bans": s.bans.list(),
+		})
+	case http.MethodDelete:
+		ip := r.URL.Query().Get("ip")
+		if ip != "" {
+			addr, err := netip.ParseAddr(ip)
+			if err != nil {
+				http.Error(w, "Invalid ip", http.StatusBadRequest)
+				return
+			}
+			ip = addr.Unmap().String()
+		}
+		cleared := s.bans.unban(ip)
+		s.logger.Printf("[Ban] Cleared %d bans via admin API (ip: %q)", cleared, ip)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":  "ok",
+			"cleared": cleared,
+		})
+	default:
+		w.Header().Set("Allow", "GET, HEAD, DELETE")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAdminAudit 查询资源管理操作的审计日志（GET），最新的记录在前
+// 参数 site、user、credential、resource、action、resource_id、region、result 精确匹配，
+// since、until 为 RFC 3339 时间，limit 为最多返回的条数（默认 100，最多 1000）
+func (s *Server) handleAdminAudit(w http.ResponseWriter, r *http.Request) {
+	if s.auditLog == nil {
+		http.Error(w, "Audit log is not enabled", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	values := r.URL.Query()
+	query := AuditQuery{
+		Site:       values.Get("site"),
+		User:       values.Get("user"),
+		Credential: values.Get("credential"),
+		Resource:   values.Get("resource"),
+		Action:     values.Get("action"),
+		ResourceID: values.Get("resource_id"),
+		Region:     values.Get("region"),
+		Result:     values.Get("result"),
+	}
+	for name, target := range map[string]*time.Time{"since": &query.Since, "until": &query.Until} {
+		if value := values.Get(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				http.Error(w, "Invalid "+name, http.StatusBadRequest)
+				return
+			}
+			*target = parsed
+		}
+	}
+	if value := values.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > 1000 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		query.Limit = limit
+	}
+
+	entries, err := s.auditLog.Query(query)
+	if err != nil {
+		s.logger.Printf("[Audit] Query failed: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"entries": entries,
+	})
+}
+
+// handleAdminInventory 立即生成一次资产快照（POST），在后台执行，返回 202；已有快照在进行时返回 409
+func (s *Server) handleAdminInventory(w http.ResponseWriter, r *http.Request) {
+	if s.inventory.Dir == "" || s.credentials == nil {
+		http.Error(w, "Inventory is not enabled", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.inventoryMu.TryLock() {
+		http.Error(w, "Inventory snapshot is already running", http.StatusConflict)
+		return
+	}
+
+	go func() {
+		defer s.inventoryMu.Unlock()
+		s.snapshotInventory(context.Background())
+	}()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "started"})
+}
+
+// handleHealth 健康检查，不需要授权；启用并发限制时附带当前的并发和排队情况
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request, state *siteState) {
+	health := map[string]interface{}{
+		"status":    "ok",
+		"sites":     len(state.siteConfigs),
+		"loaded_at": state.loadedAt,
+	}
+	if s.concurrency != nil {
+		health["concurrency"] = s.concurrency.stats()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(health)
+}
@@ -0,0 +1,42 @@
+package hub
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthorizeAdmin(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		remote string
+		header map[string]string
+		want   bool
+	}{
+		{"loopback without token", "", "127.0.0.1:1234", nil, true},
+		{"ipv6 loopback without token", "", "[::1]:1234", nil, true},
+		{"remote without token", "", "203.0.113.5:1234", nil, false},
+		{"loopback proxy with X-Forwarded-For", "", "127.0.0.1:1234", map[string]string{"X-Forwarded-For": "203.0.113.5"}, false},
+		{"loopback proxy with Forwarded", "", "127.0.0.1:1234", map[string]string{"Forwarded": "for=203.0.113.5"}, false},
+		{"loopback proxy with X-Real-IP", "", "127.0.0.1:1234", map[string]string{"X-Real-IP": "127.0.0.1"}, false},
+		{"token required on loopback", "admin-token", "127.0.0.1:1234", nil, false},
+		{"token accepted", "admin-token", "203.0.113.5:1234", map[string]string{"Authorization": "Bearer admin-token"}, true},
+		{"token accepted through proxy", "admin-token", "127.0.0.1:1234", map[string]string{"Authorization": "Bearer admin-token", "X-Forwarded-For": "203.0.113.5"}, true},
+		{"wrong token", "admin-token", "127.0.0.1:1234", map[string]string{"Authorization": "Bearer other"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newAPITestServer(t, "http://127.0.0.1:1", Options{AdminToken: tt.token})
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/_admin/bans", nil)
+			req.RemoteAddr = tt.remote
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			req, _ = srv.withRequestInfo(req)
+			if got := srv.authorizeAdmin(req); got != tt.want {
+				t.Errorf("authorizeAdmin = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
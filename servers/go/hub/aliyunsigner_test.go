@@ -0,0 +1,29 @@
+package hub
+
+import (
+	"net/http"
+	"testing"
+)
+
+// 阿里云 ECS 文档“签名机制”中 DescribeRegions 的示例
+func TestSignAliyunRPC(t *testing.T) {
+	params := map[string]string{
+		"AccessKeyId":      "testid",
+		"Action":           "DescribeRegions",
+		"Format":           "XML",
+		"SignatureMethod":  "HMAC-SHA1",
+		"SignatureNonce":   "3ee8c1b8-83d3-44af-a94f-4e0ad82fd6cf",
+		"SignatureVersion": "1.0",
+		"Timestamp":        "2016-02-23T12:46:24Z",
+		"Version":          "2014-05-26",
+	}
+	wantStringToSign := "GET&%2F&AccessKeyId%3Dtestid%26Action%3DDescribeRegions%26Format%3DXML" +
+		"%26SignatureMethod%3DHMAC-SHA1%26SignatureNonce%3D3ee8c1b8-83d3-44af-a94f-4e0ad82fd6cf" +
+		"%26SignatureVersion%3D1.0%26Timestamp%3D2016-02-23T12%253A46%253A24Z%26Version%3D2014-05-26"
+	if got := AliyunRPCStringToSign(http.MethodGet, params); got != wantStringToSign {
+		t.Errorf("string to sign = %s\nwant %s", got, wantStringToSign)
+	}
+	if got, want := SignAliyunRPC(http.MethodGet, params, "testsecret"), "OLeaidS1JvxuMvnyHOwuJ+uX5qY="; got != want {
+		t.Errorf("signature = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,273 @@
+package hub
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// apiSessionCookie 浏览器登录后保存会话的 Cookie 名称
+const apiSessionCookie = "jinja_hub_session"
+
+// APIUser 可以使用服务端凭证接口（签名代理、聚合查询、资源管理、导出、资产快照和状态推送）的用户
+type APIUser struct {
+	Name        string   // 用户名，写入审计日志，策略规则按它匹配
+	TokenSHA256 string   // 访问令牌的 SHA-256（十六进制），服务器不保存令牌明文
+	Sites       []string // 允许访问的站点，为空表示所有站点
+}
+
+// Validate 检查配置是否可用
+func (u APIUser) Validate() error {
+	if u.Name == "" || strings.ContainsAny(u.Name, "\n\x00") {
+		return fmt.Errorf("name is required")
+	}
+	if digest, err := hex.DecodeString(u.TokenSHA256); err != nil || len(digest) != sha256.Size {
+		return fmt.Errorf("%s: token_sha256 must be a hex-encoded SHA-256 digest", u.Name)
+	}
+	if strings.EqualFold(u.TokenSHA256, HashAPIToken("")) {
+		return fmt.Errorf("%s: token must not be empty", u.Name)
+	}
+	return nil
+}
+
+// allowsSite 用户是否可以访问该站点
+func (u APIUser) allowsSite(site string) bool {
+	return len(u.Sites) == 0 || slices.Contains(u.Sites, site)
+}
+
+// HashAPIToken 返回访问令牌的 SHA-256（十六进制），用于填写 APIUser.TokenSHA256
+func HashAPIToken(token string) string {
+	digest := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(digest[:])
+}
+
+// apiUsers 按令牌摘要和用户名查找用户
+type apiUsers struct {
+	byDigest map[string]APIUser
+	byName   map[string]APIUser
+}
+
+func newAPIUsers(users []APIUser) (*apiUsers, error) {
+	u := &apiUsers{byDigest: make(map[string]APIUser), byName: make(map[string]APIUser)}
+	for i, user := range users {
+		if err := user.Validate(); err != nil {
+			return nil, fmt.Errorf("[%d]: %w", i, err)
+		}
+		user.TokenSHA256 = strings.ToLower(user.TokenSHA256)
+		if _, exists := u.byName[user.Name]; exists {
+			return nil, fmt.Errorf("[%d]: duplicate name %s", i, user.Name)
+		}
+		if _, exists := u.byDigest[user.TokenSHA256]; exists {
+			return nil, fmt.Errorf("[%d]: %s shares its token with another user", i, user.Name)
+		}
+		u.byDigest[user.TokenSHA256] = user
+		u.byName[user.Name] = user
+	}
+	return u, nil
+}
+
+// lookupToken 按令牌查找用户，比较摘要时不泄露时间差
+func (u *apiUsers) lookupToken(token string) (APIUser, bool) {
+	digest := HashAPIToken(token)
+	for known, user := range u.byDigest {
+		if subtle.ConstantTimeCompare([]byte(known), []byte(digest)) == 1 {
+			return user, true
+		}
+	}
+	return APIUser{}, false
+}
+
+// apiSessions 签发和校验浏览器会话
+// 会话绑定用户名和令牌摘要，更换令牌后旧会话失效；签名密钥在启动时随机生成，重启后需要重新登录
+type apiSessions struct {
+	key [32]byte
+	ttl time.Duration
+}
+
+func newAPISessions(ttl time.Duration) *apiSessions {
+	s := &apiSessions{ttl: ttl}
+	rand.Read(s.key[:])
+	return s
+}
+
+// issue 签发会话：base64(过期时间 + 用户名).base64(HMAC-SHA256)
+func (s *apiSessions) issue(user APIUser) string {
+	payload := binary.BigEndian.AppendUint64(nil, uint64(time.Now().Add(s.ttl).Unix()))
+	payload = append(payload, user.Name...)
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.sign(encoded, user.TokenSHA256)
+}
+
+// verify 校验会话，返回会话所属的用户
+func (s *apiSessions) verify(value string, users *apiUsers) (APIUser, bool) {
+	encoded, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return APIUser{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(payload) <= 8 {
+		return APIUser{}, false
+	}
+	if time.Now().After(time.Unix(int64(binary.BigEndian.Uint64(payload)), 0)) {
+		return APIUser{}, false
+	}
+	user, ok := users.byName[string(payload[8:])]
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.sign(encoded, user.TokenSHA256))) {
+		return APIUser{}, false
+	}
+	return user, true
+}
+
+func (s *apiSessions) sign(encoded, digest string) string {
+	mac := hmac.New(sha256.New, s.key[:])
+	mac.Write([]byte(encoded + "\n" + digest))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// authenticateAPI 识别调用服务端凭证接口的用户，失败时写入错误响应并返回 false
+func (s *Server) authenticateAPI(w http.ResponseWriter, r *http.Request, siteName, origin string) (APIUser, bool) {
+	user, apiErr := s.identifyAPIUser(r, siteName, origin)
+	if apiErr != nil {
+		if apiErr.Status == http.StatusUnauthorized {
+			writeAPIUnauthorized(w, apiErr.Message)
+		} else {
+			writeAPIProxyError(w, apiErr.Status, apiErr.Code, apiErr.Message)
+		}
+		return APIUser{}, false
+	}
+	return user, true
+}
+
+// identifyAPIUser 非浏览器客户端使用 Authorization: Bearer 访问令牌；浏览器通过 api/session 登录后使用会话 Cookie，
+// Cookie 会被浏览器自动带上，因此会话请求还必须是浏览器标明的同源请求
+func (s *Server) identifyAPIUser(r *http.Request, siteName, origin string) (APIUser, *apiError) {
+	var user APIUser
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		if user, ok = s.apiUsers.lookupToken(token); !ok {
+			return APIUser{}, &apiError{http.StatusUnauthorized, "Unauthorized", "Invalid access token"}
+		}
+	} else if cookie, err := r.Cookie(apiSessionCookie); err == nil {
+		if user, ok = s.apiSessions.verify(cookie.Value, s.apiUsers); !ok {
+			return APIUser{}, &apiError{http.StatusUnauthorized, "Unauthorized", "Session has expired, sign in again"}
+		}
+		if !isSameOriginRequest(r, origin) {
+			return APIUser{}, &apiError{http.StatusForbidden, "Forbidden", "Cross-origin requests are not allowed"}
+		}
+	} else {
+		return APIUser{}, &apiError{http.StatusUnauthorized, "Unauthorized", "Sign in with an access token to use server-side credentials"}
+	}
+	if !user.allowsSite(siteName) {
+		return APIUser{}, &apiError{http.StatusForbidden, "Forbidden", "User is not allowed to access site: " + siteName}
+	}
+	return user, nil
+}
+
+// writeAPIUnauthorized 返回 401，前端收到 Unauthorized 后提示输入访问令牌
+func writeAPIUnauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="jinja-hub"`)
+	writeAPIProxyError(w, http.StatusUnauthorized, "Unauthorized", message)
+}
+
+// handleAPISession 处理 /{site}/api/session：
+// POST 以表单参数 token 登录，设置 HttpOnly、SameSite=Strict 的会话 Cookie；GET 返回当前用户；DELETE 退出登录
+// 站点没有配置凭证时返回 404
+func (s *Server) handleAPISession(w http.ResponseWriter, r *http.Request, siteName, origin string) {
+	if s.credentials == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if _, ok := s.credentials.Credential(siteName); !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	cookie := &http.Cookie{
+		Name:     apiSessionCookie,
+		Path:     s.prefix + "/",
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(origin, "https://"),
+		SameSite: http.SameSiteStrictMode,
+	}
+	switch r.Method {
+	case http.MethodGet:
+		user, ok := s.authenticateAPI(w, r, siteName, origin)
+		if !ok {
+			return
+		}
+		writeAPISession(w, user)
+	case http.MethodPost:
+		if !isSameOriginRequest(r, origin) {
+			writeAPIProxyError(w, http.StatusForbidden, "Forbidden", "Cross-origin requests are not allowed")
+			return
+		}
+		user, ok := s.apiUsers.lookupToken(r.PostFormValue("token"))
+		if !ok {
+			writeAPIUnauthorized(w, "Invalid access token")
+			return
+		}
+		if !user.allowsSite(siteName) {
+			writeAPIProxyError(w, http.StatusForbidden, "Forbidden", "User is not allowed to access site: "+siteName)
+			return
+		}
+		cookie.Value = s.apiSessions.issue(user)
+		cookie.MaxAge = int(s.apiSessions.ttl / time.Second)
+		http.SetCookie(w, cookie)
+		writeAPISession(w, user)
+	case http.MethodDelete:
+		if !isSameOriginRequest(r, origin) {
+			writeAPIProxyError(w, http.StatusForbidden, "Forbidden", "Cross-origin requests are not allowed")
+			return
+		}
+		cookie.MaxAge = -1
+		http.SetCookie(w, cookie)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		writeAPIProxyError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "Only GET, POST and DELETE are allowed")
+	}
+}
+
+func writeAPISession(w http.ResponseWriter, user APIUser) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]string{"User": user.Name})
+}
+
+// proxyActionAllowed 签名代理只转发站点明确允许的接口（默认拒绝）：
+// 表格的 apiFunction、api.regions 的地域列表接口，以及 api.allowedActions 中列出的接口名
+// （支持 path.Match 通配符，例如 "Describe*"，需要显式配置）
+func proxyActionAllowed(config Config, product, action string) bool {
+	tables, _ := config["tables"].(map[string]interface{})
+	for _, entry := range tables {
+		table, _ := entry.(map[string]interface{})
+		if table["apiFunction"] == action {
+			if tableProduct, _ := table["product"].(string); tableProduct == "" || tableProduct == product {
+				return true
+			}
+		}
+	}
+	if list, ok := apiRegionListFor(config); ok && list.Product == product && list.Action == action {
+		return true
+	}
+	products, _ := config["api"].(map[string]interface{})
+	patterns, _ := products["allowedActions"].([]interface{})
+	for _, item := range patterns {
+		if pattern, ok := item.(string); ok {
+			if matched, _ := path.Match(pattern, action); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
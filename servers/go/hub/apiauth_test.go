@@ -0,0 +1,136 @@
+package hub
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// newAPITestServer 创建只有一个 aliyun 站点的 Server，云服务 API 指向 upstream
+func newAPITestServer(t *testing.T, upstream string, opts Options) *Server {
+	t.Helper()
+	opts.SitesFS = fstest.MapFS{
+		"sites.json": {Data: []byte(`{"sites": {"aliyun": {"name": "Aliyun", "enabled": true}}}`)},
+		"aliyun/config.json": {Data: []byte(`{
+			"api": {
+				"ecs": {"version": "2014-05-26", "endpoint": "` + upstream + `"},
+				"allowedActions": ["DescribeZones", "RunInstances"]
+			},
+			"tables": {"ecs_instances": {"product": "ecs", "apiFunction": "DescribeInstances", "dataPath": "Instances.Instance", "queryParams": ["Status"],
+				"fields": [{"label": "ID", "field": "InstanceId", "showInTable": true}]}},
+			"resource_manage": {"ecs_instance": {"actions": [
+				{"name": "stop", "api": {"product": "ecs", "action": "StopInstance", "idParam": "InstanceId"}}
+			]}}
+		}`)},
+		"aliyun/templates/pages/login.html": {Data: []byte(`login`)},
+		"_home/templates/index.html":        {Data: []byte(`home`)},
+	}
+	opts.Credentials = StaticCredentials{"*": {AccessKeyID: "LTAI0000000000000000", AccessKeySecret: "secret"}}
+	opts.Logger = log.New(io.Discard, "", 0)
+	srv, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+func TestAPIProxyAuthentication(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"RequestId": "req-1"}`)
+	}))
+	defer upstream.Close()
+
+	srv := newAPITestServer(t, upstream.URL, Options{APIUsers: []APIUser{
+		{Name: "alice", TokenSHA256: HashAPIToken("alice-token")},
+		{Name: "bob", TokenSHA256: HashAPIToken("bob-token"), Sites: []string{"other"}},
+	}})
+
+	proxyURL := "http://example.com/aliyun/api/proxy/ecs/"
+	tests := []struct {
+		name   string
+		action string
+		header map[string]string
+		want   int
+	}{
+		{"no credentials", "DescribeInstances", nil, http.StatusUnauthorized},
+		{"no credentials, same-origin headers", "DescribeInstances", map[string]string{"Sec-Fetch-Site": "same-origin"}, http.StatusUnauthorized},
+		{"invalid token", "DescribeInstances", map[string]string{"Authorization": "Bearer wrong"}, http.StatusUnauthorized},
+		{"user without access to site", "DescribeInstances", map[string]string{"Authorization": "Bearer bob-token"}, http.StatusForbidden},
+		{"table action", "DescribeInstances", map[string]string{"Authorization": "Bearer alice-token"}, http.StatusOK},
+		{"allowed action", "DescribeZones", map[string]string{"Authorization": "Bearer alice-token"}, http.StatusOK},
+		{"action not on allowlist", "DeleteInstance", map[string]string{"Authorization": "Bearer alice-token"}, http.StatusForbidden},
+		{"managed action", "StopInstance", map[string]string{"Authorization": "Bearer alice-token"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, proxyURL+tt.action+"?RegionId=cn-hangzhou", nil)
+			for key, value := range tt.header {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestAPISession(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{}`)
+	}))
+	defer upstream.Close()
+	srv := newAPITestServer(t, upstream.URL, Options{APIUsers: []APIUser{{Name: "alice", TokenSHA256: HashAPIToken("alice-token")}}})
+
+	login := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "http://example.com/aliyun/api/session", strings.NewReader(url.Values{"token": {token}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Origin", "http://example.com")
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := login("wrong"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("login with wrong token: status = %d", rec.Code)
+	}
+	rec := login("alice-token")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login: status = %d: %s", rec.Code, rec.Body)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || !cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteStrictMode {
+		t.Fatalf("session cookie = %+v", cookies)
+	}
+
+	call := func(header map[string]string) int {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/aliyun/api/proxy/ecs/DescribeInstances?RegionId=cn-hangzhou", nil)
+		req.AddCookie(cookies[0])
+		for key, value := range header {
+			req.Header.Set(key, value)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if status := call(map[string]string{"Sec-Fetch-Site": "same-origin"}); status != http.StatusOK {
+		t.Errorf("same-origin session request: status = %d", status)
+	}
+	if status := call(nil); status != http.StatusForbidden {
+		t.Errorf("session request without Origin or Sec-Fetch-Site: status = %d", status)
+	}
+	if status := call(map[string]string{"Origin": "http://evil.example"}); status != http.StatusForbidden {
+		t.Errorf("cross-origin session request: status = %d", status)
+	}
+
+	cookies[0].Value = "tampered." + strings.SplitN(cookies[0].Value, ".", 2)[1]
+	if status := call(map[string]string{"Sec-Fetch-Site": "same-origin"}); status != http.StatusUnauthorized {
+		t.Errorf("tampered session: status = %d", status)
+	}
+}
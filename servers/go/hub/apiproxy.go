@@ -0,0 +1,289 @@
+package hub
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	apiProxyNamePattern   = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*$`)
+	apiProxyRegionPattern = regexp.MustCompile(`^[a-z0-9-]{1,64}$`)
+)
+
+// apiProxyTimeout 默认 HTTP 客户端访问云服务 API 的超时时间
+const apiProxyTimeout = 30 * time.Second
+
+// apiProduct 站点配置 api.{product} 中的产品定义
+type apiProduct struct {
+	Version  string
+	Endpoint string // 可以包含 {region}
+	Service  string // 签名使用的服务名，未配置时为产品名
+	Region   string // 请求没有指定地域时使用的默认地域
+}
+
+// apiProductFor 读取站点配置中的产品定义
+func apiProductFor(config Config, product string) (apiProduct, bool) {
+	products, _ := config["api"].(map[string]interface{})
+	entry, _ := products[product].(map[string]interface{})
+	version, _ := entry["version"].(string)
+	endpoint, _ := entry["endpoint"].(string)
+	if version == "" || endpoint == "" {
+		return apiProduct{}, false
+	}
+	service, _ := entry["service"].(string)
+	if service == "" {
+		service = product
+	}
+	region, _ := entry["region"].(string)
+	return apiProduct{Version: version, Endpoint: endpoint, Service: service, Region: region}, true
+}
+
+// apiSignerName 站点配置 api.signer 选择的签名算法，未配置时为阿里云 RPC
+func apiSignerName(config Config) string {
+	products, _ := config["api"].(map[string]interface{})
+	if name, ok := products["signer"].(string); ok && name != "" {
+		return name
+	}
+	return SignerAliyunRPC
+}
+
+// handleAPIProxy 处理 /{site}/api/proxy/{product}/{action}：
+// 使用服务端保存的凭证，按站点配置 api.signer 选择的签名算法签名后转发，浏览器不接触 AccessKey
+// 站点没有配置凭证时返回 404；调用者必须是已登录的 API 用户（见 authenticateAPI），
+// 只转发站点允许的接口（见 proxyActionAllowed）；resource_manage 中的操作使用的接口必须通过 api/manage/ 调用。
+// 非只读接口（见 isReadOnlyAction）和 api/manage/ 一样按策略放行、拒绝或要求确认（confirm_token 参数），并写入审计日志
+func (s *Server) handleAPIProxy(w http.ResponseWriter, r *http.Request, state *siteState, siteName, origin, product, action string) {
+	if s.credentials == nil {
+		http.NotFound(w, r)
+		return
+	}
+	credential, ok := s.credentials.Credential(siteName)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		writeAPIProxyError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "Only GET and POST are allowed")
+		return
+	}
+	user, ok := s.authenticateAPI(w, r, siteName, origin)
+	if !ok {
+		return
+	}
+
+	// JSON 请求体原样交给签名算法；查询参数和表单参数合并，同名参数只取第一个值
+	var body []byte
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); r.Method == http.MethodPost && mediaType == "application/json" {
+		var err error
+		if body, err = io.ReadAll(r.Body); err != nil {
+			writeAPIProxyError(w, http.StatusBadRequest, "InvalidParameter", err.Error())
+			return
+		}
+	}
+	if err := r.ParseForm(); err != nil {
+		writeAPIProxyError(w, http.StatusBadRequest, "InvalidParameter", err.Error())
+		return
+	}
+	params := make(map[string]string, len(r.Form))
+	for key, values := range r.Form {
+		if len(values) > 0 && key != "confirm_token" {
+			params[key] = values[0]
+		}
+	}
+
+	config := state.siteConfigs[siteName]
+	readOnly := isReadOnlyAction(action)
+	entry := AuditEntry{
+		Time:        time.Now(),
+		Site:        siteName,
+		User:        user.Name,
+		ClientIP:    ClientIP(r),
+		UserAgent:   r.UserAgent(),
+		Credential:  credential.Name,
+		AccessKeyID: maskAccessKeyID(credential.AccessKeyID),
+		API:         product + "/" + action,
+		Params:      params,
+	}
+	if signer, ok := s.signers[apiSignerName(config)]; ok {
+		entry.Region = params[signer.RegionParam()]
+	}
+	reject := func(code, message string) {
+		if !readOnly {
+			entry.Result, entry.Code, entry.Message = AuditDenied, code, message
+			s.audit(entry)
+		}
+		writeAPIProxyError(w, http.StatusForbidden, code, message)
+	}
+	if isManagedAPI(config, product, action) {
+		reject("ManagedAction", "Action must be called through api/manage/: "+action)
+		return
+	}
+	if !proxyActionAllowed(config, product, action) {
+		reject("ActionNotAllowed", "Action is not allowed through the proxy: "+product+"/"+action)
+		return
+	}
+	if !readOnly {
+		effect := s.policy.decide(policyRequest{Site: siteName, User: user.Name, Credential: credential.Name, API: entry.API})
+		if effect == PolicyDeny {
+			s.denyAudited(w, entry)
+			return
+		}
+		if effect == PolicyConfirm {
+			if !s.confirmAudited(w, r.Form.Get("confirm_token"), apiProxyConfirmSubject(entry, body), entry) {
+				return
+			}
+		}
+	}
+
+	req, apiErr := s.newAPIRequest(r.Context(), config, siteName, credential, product, action, params, body)
+	if apiErr != nil {
+		if !readOnly {
+			entry.Result, entry.Code, entry.Message = AuditFailed, apiErr.Code, apiErr.Message
+			s.audit(entry)
+		}
+		writeAPIProxyError(w, apiErr.Status, apiErr.Code, apiErr.Message)
+		return
+	}
+	if !readOnly {
+		s.doAudited(w, req, entry)
+		return
+	}
+	resp, err := s.apiClient.Do(req)
+	if err != nil {
+		if r.Context().Err() == nil {
+			s.logger.Printf("[APIProxy] %s %s/%s: %v", siteName, product, action, upstreamError(err))
+		}
+		writeAPIProxyError(w, http.StatusBadGateway, "ProxyError", "Upstream request failed")
+		return
+	}
+	defer resp.Body.Close()
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// isReadOnlyAction 按云服务 API 的命名惯例判断接口是否只读（Describe、List、Get、Query 开头），
+// 其余接口都视为会修改资源
+func isReadOnlyAction(action string) bool {
+	for _, prefix := range []string{"Describe", "List", "Get", "Query"} {
+		if rest, ok := strings.CutPrefix(action, prefix); ok && (rest == "" || rest[0] >= 'A' && rest[0] <= 'Z') {
+			return true
+		}
+	}
+	return false
+}
+
+// apiProxyConfirmSubject 确认令牌绑定的内容：站点、用户、凭证、接口、排序后的参数和 JSON 请求体的摘要
+func apiProxyConfirmSubject(entry AuditEntry, body []byte) string {
+	keys := make([]string, 0, len(entry.Params))
+	for key := range entry.Params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := []string{entry.Site, entry.User, entry.Credential, entry.API}
+	for _, key := range keys {
+		parts = append(parts, key+"="+entry.Params[key])
+	}
+	digest := sha256.Sum256(body)
+	parts = append(parts, hex.EncodeToString(digest[:]))
+	return strings.Join(parts, "\n")
+}
+
+// apiError 签名代理拒绝请求时返回给前端的错误
+type apiError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+// newAPIRequest 按站点配置选择签名算法和产品，校验接口名和地域后构造签名请求
+// params 为请求参数（地域取自签名算法的 RegionParam），body 为 JSON 请求体（没有时为 nil）
+func (s *Server) newAPIRequest(ctx context.Context, config Config, siteName string, credential Credential, product, action string, params map[string]string, body []byte) (*http.Request, *apiError) {
+	signerName := apiSignerName(config)
+	signer, ok := s.signers[signerName]
+	if !ok {
+		s.logger.Printf("[APIProxy] %s: unknown signer %q", siteName, signerName)
+		return nil, &apiError{http.StatusInternalServerError, "UnknownSigner", "Signer is not available: " + signerName}
+	}
+	productConfig, ok := apiProductFor(config, product)
+	if !ok || !apiProxyNamePattern.MatchString(product) {
+		return nil, &apiError{http.StatusNotFound, "UnknownProduct", "Product is not configured: " + product}
+	}
+	if !apiProxyNamePattern.MatchString(action) {
+		return nil, &apiError{http.StatusBadRequest, "InvalidAction", "Invalid action: " + action}
+	}
+
+	call := APICall{Service: productConfig.Service, Version: productConfig.Version, Action: action, Params: params, Body: body}
+	call.Region = params[signer.RegionParam()]
+	if call.Region == "" {
+		call.Region = productConfig.Region
+	}
+	if call.Region != "" && !apiProxyRegionPattern.MatchString(call.Region) {
+		return nil, &apiError{http.StatusBadRequest, "InvalidRegion", "Invalid " + signer.RegionParam()}
+	}
+	call.Endpoint = productConfig.Endpoint
+	if strings.Contains(call.Endpoint, "{region}") {
+		if call.Region == "" {
+			return nil, &apiError{http.StatusBadRequest, "MissingRegion", signer.RegionParam() + " is required"}
+		}
+		call.Endpoint = strings.ReplaceAll(call.Endpoint, "{region}", call.Region)
+	}
+
+	req, err := signer.NewRequest(ctx, call, credential)
+	if err != nil {
+		return nil, &apiError{http.StatusBadRequest, "InvalidParameter", err.Error()}
+	}
+	return req, nil
+}
+
+// upstreamError 去掉 *url.Error 中带签名的完整 URL，用于记录日志
+func upstreamError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// isSameOriginRequest 请求是否由浏览器标明为同源：Origin 与本站相同或 Sec-Fetch-Site 为同源，两者有冲突时返回 false；
+// 两个请求头都没有的请求无法判断来源，返回 false
+func isSameOriginRequest(r *http.Request, origin string) bool {
+	requestOrigin, fetchSite := r.Header.Get("Origin"), r.Header.Get("Sec-Fetch-Site")
+	if requestOrigin == "" && fetchSite == "" {
+		return false
+	}
+	if requestOrigin != "" && !strings.EqualFold(requestOrigin, origin) {
+		return false
+	}
+	switch fetchSite {
+	case "", "same-origin", "none":
+		return true
+	default:
+		return false
+	}
+}
+
+// writeAPIProxyError 以云服务 API 的错误格式返回，前端按 Code/Message 统一处理
+func writeAPIProxyError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"Code": code, "Message": message})
+}
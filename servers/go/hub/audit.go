@@ -0,0 +1,152 @@
+package hub
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 审计记录的结果
+const (
+	AuditSuccess = "success" // 云服务 API 调用成功
+	AuditFailed  = "failed"  // 参数错误、网络错误或云服务 API 返回错误
+	AuditDenied  = "denied"  // 被策略或接口白名单拒绝，没有调用云服务 API
+
+	AuditConfirmationRequired = "confirmation_required" // 需要确认，已签发确认令牌，没有调用云服务 API
+)
+
+// AuditEntry 一次资源管理操作或签名代理中非只读接口调用的审计记录
+type AuditEntry struct {
+	Time        time.Time         `json:"time"`
+	Site        string            `json:"site"`
+	User        string            `json:"user"` // 已登录的 API 用户（APIUser.Name）
+	ClientIP    string            `json:"client_ip"`
+	UserAgent   string            `json:"user_agent,omitempty"`
+	Credential  string            `json:"credential"`       // 凭证名称
+	AccessKeyID string            `json:"access_key_id"`    // 打码的 AccessKey ID
+	Resource    string            `json:"resource"`         // 通过签名代理直接调用时为空
+	Action      string            `json:"action"`           // 通过签名代理直接调用时为空
+	API         string            `json:"api"`              // 调用的云服务 API，例如 vpc/ReleaseEipAddress
+	Params      map[string]string `json:"params,omitempty"` // 通过签名代理直接调用时的请求参数
+	Region      string            `json:"region"`
+	ResourceID  string            `json:"resource_id"`
+	Result      string            `json:"result"`
+	Code        string            `json:"code,omitempty"` // 失败或拒绝时的错误码
+	Message     string            `json:"message,omitempty"`
+	RequestID   string            `json:"request_id,omitempty"` // 云服务 API 返回的 RequestId
+}
+
+// AuditQuery 审计记录的查询条件，空字段不限制
+type AuditQuery struct {
+	Site       string
+	User       string
+	Credential string
+	Resource   string
+	Action     string
+	ResourceID string
+	Region     string
+	Result     string
+	Since      time.Time
+	Until      time.Time
+	Limit      int // 最多返回的条数，0 表示 100
+}
+
+// matches 记录是否满足查询条件
+func (q AuditQuery) matches(entry AuditEntry) bool {
+	return (q.Site == "" || entry.Site == q.Site) &&
+		(q.User == "" || entry.User == q.User) &&
+		(q.Credential == "" || entry.Credential == q.Credential) &&
+		(q.Resource == "" || entry.Resource == q.Resource) &&
+		(q.Action == "" || entry.Action == q.Action) &&
+		(q.ResourceID == "" || entry.ResourceID == q.ResourceID) &&
+		(q.Region == "" || entry.Region == q.Region) &&
+		(q.Result == "" || entry.Result == q.Result) &&
+		(q.Since.IsZero() || !entry.Time.Before(q.Since)) &&
+		(q.Until.IsZero() || entry.Time.Before(q.Until))
+}
+
+// AuditLog 资源管理操作的审计日志，只追加不修改
+type AuditLog interface {
+	// Append 追加一条记录
+	Append(entry AuditEntry) error
+	// Query 按条件查询，最新的记录在前
+	Query(query AuditQuery) ([]AuditEntry, error)
+}
+
+// FileAuditLog 以 JSON Lines 格式追加写入文件的审计日志
+type FileAuditLog struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenAuditLog 以追加模式打开（不存在时创建）审计日志文件
+func OpenAuditLog(filePath string) (*FileAuditLog, error) {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileAuditLog{file: file}, nil
+}
+
+// Append 写入一行 JSON，每条记录单独一次 write，并发写入不会交错
+func (l *FileAuditLog) Append(entry AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.file.Write(append(data, '\n'))
+	return err
+}
+
+// Query 从头读取整个文件，保留最后 Limit 条满足条件的记录；无法解析的行被跳过
+func (l *FileAuditLog) Query(query AuditQuery) ([]AuditEntry, error) {
+	if query.Limit <= 0 {
+		query.Limit = 100
+	}
+	file, err := os.Open(l.file.Name())
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// 环形缓冲区，只保留最新的 Limit 条
+	ring := make([]AuditEntry, 0, min(query.Limit, 1024))
+	next := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil || !query.matches(entry) {
+			continue
+		}
+		if len(ring) < query.Limit {
+			ring = append(ring, entry)
+		} else {
+			ring[next] = entry
+			next = (next + 1) % query.Limit
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", l.file.Name(), err)
+	}
+
+	entries := make([]AuditEntry, 0, len(ring))
+	for i := len(ring) - 1; i >= 0; i-- {
+		entries = append(entries, ring[(next+i)%len(ring)])
+	}
+	return entries, nil
+}
+
+// Close 关闭文件
+func (l *FileAuditLog) Close() error {
+	return l.file.Close()
+}
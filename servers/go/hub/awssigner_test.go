@@ -0,0 +1,38 @@
+package hub
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// AWS Signature Version 4 测试套件（aws-sig-v4-test-suite）中的用例
+func TestSignAWSV4(t *testing.T) {
+	credential := Credential{AccessKeyID: "AKIDEXAMPLE", AccessKeySecret: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		url       string
+		signature string
+	}{
+		{"get-vanilla", "https://example.amazonaws.com/", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"get-vanilla-query-order-key-case", "https://example.amazonaws.com/?Param2=value2&Param1=value1", "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			SignAWSV4(req, nil, credential, "us-east-1", "service", now)
+			want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=host;x-amz-date, Signature=" + tt.signature
+			if got := req.Header.Get("Authorization"); got != want {
+				t.Errorf("Authorization = %s\nwant %s", got, want)
+			}
+			if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+				t.Errorf("X-Amz-Date = %s", got)
+			}
+		})
+	}
+}
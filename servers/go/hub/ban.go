@@ -0,0 +1,305 @@
+package hub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 可疑请求的计分
+const (
+	banScoreNotFound   = 1  // 404
+	banScoreBadRequest = 2  // 400
+	banScoreForbidden  = 2  // 403
+	banScoreTraversal  = 10 // 路径中包含 ..（无论响应状态）
+)
+
+// BanConfig 自动封禁配置（类似 fail2ban）
+// 客户端在计分窗口内的 404/403/400 响应和路径遍历尝试累计达到 Threshold 分时被封禁，
+// 再次被封禁时时长翻倍，直到 MaxDuration
+type BanConfig struct {
+	Threshold   int           // 封禁分数，0 表示不启用
+	Window      time.Duration // 计分窗口，0 表示 1 分钟
+	Duration    time.Duration // 第一次封禁的时长，0 表示 10 分钟
+	MaxDuration time.Duration // 封禁时长上限，0 表示 24 小时；封禁结束后记录保留这么久，用于递增时长
+	File        string        // 封禁列表的持久化文件，为空时不持久化
+}
+
+// Validate 检查配置是否可用
+func (c BanConfig) Validate() error {
+	if c.Threshold < 0 || c.Window < 0 || c.Duration < 0 || c.MaxDuration < 0 {
+		return fmt.Errorf("threshold, window, duration and max_duration must not be negative")
+	}
+	if c.MaxDuration > 0 && c.Duration > c.MaxDuration {
+		return fmt.Errorf("duration must not exceed max_duration")
+	}
+	return nil
+}
+
+// Ban 一条封禁记录
+type Ban struct {
+	IP       string    `json:"ip"`
+	Until    time.Time `json:"until"`
+	Offenses int       `json:"offenses"` // 累计被封禁的次数
+	Reason   string    `json:"reason"`   // 触发封禁的最后一个请求
+}
+
+// banList 计分和封禁状态
+type banList struct {
+	config BanConfig
+	logger *log.Logger
+
+	mu        sync.Mutex
+	scores    map[string]*banScore
+	bans      map[string]*Ban // 包括已经结束但还在保留期内的记录
+	lastSweep time.Time
+
+	saveMu sync.Mutex
+}
+
+type banScore struct {
+	score       int
+	windowStart time.Time
+}
+
+// newBanList 创建封禁列表并加载持久化文件，文件损坏时记录日志并从空列表开始
+func newBanList(config BanConfig, logger *log.Logger) *banList {
+	if config.Window <= 0 {
+		config.Window = time.Minute
+	}
+	if config.Duration <= 0 {
+		config.Duration = 10 * time.Minute
+	}
+	if config.MaxDuration <= 0 {
+		config.MaxDuration = 24 * time.Hour
+	}
+	b := &banList{
+		config: config,
+		logger: logger,
+		scores: make(map[string]*banScore),
+		bans:   make(map[string]*Ban),
+	}
+	if err := b.load(); err != nil {
+		logger.Printf("[Ban] Failed to load %s: %v", config.File, err)
+	}
+	return b
+}
+
+// banned 返回 IP 当前生效的封禁
+func (b *banList) banned(ip string) (Ban, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ban, ok := b.bans[ip]; ok && time.Now().Before(ban.Until) {
+		return *ban, true
+	}
+	return Ban{}, false
+}
+
+// observe 按响应状态和是否有路径遍历为 IP 计分，达到阈值时封禁
+func (b *banList) observe(ip string, status int, traversal bool, reason string) {
+	points := banPoints(status, traversal)
+	if points == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	now := time.Now()
+	b.sweep(now)
+
+	score, ok := b.scores[ip]
+	if !ok || now.Sub(score.windowStart) >= b.config.Window {
+		score = &banScore{windowStart: now}
+		b.scores[ip] = score
+	}
+	score.score += points
+	if score.score < b.config.Threshold {
+		b.mu.Unlock()
+		return
+	}
+
+	// 达到阈值：封禁，时长按累计次数翻倍
+	delete(b.scores, ip)
+	ban, ok := b.bans[ip]
+	if !ok {
+		ban = &Ban{IP: ip}
+		b.bans[ip] = ban
+	}
+	ban.Offenses++
+	duration := b.config.Duration
+	for i := 1; i < ban.Offenses && duration < b.config.MaxDuration; i++ {
+		duration *= 2
+	}
+	duration = min(duration, b.config.MaxDuration)
+	ban.Until = now.Add(duration)
+	ban.Reason = reason
+	b.logger.Printf("[Ban] IP: %s, Offenses: %d, Duration: %s, Last: %s - BANNED", ip, ban.Offenses, duration, reason)
+	b.mu.Unlock()
+
+	b.save()
+}
+
+// banExempt 回环地址和受信任的代理不计分，避免同机或前置的反向代理把自己封禁
+func (s *Server) banExempt(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	return addr.Unmap().IsLoopback() || s.trustedProxies.Contains(addr)
+}
+
+// banPoints 计算一个请求的可疑分数
+func banPoints(status int, traversal bool) int {
+	points := 0
+	switch status {
+	case http.StatusNotFound:
+		points = banScoreNotFound
+	case http.StatusBadRequest:
+		points = banScoreBadRequest
+	case http.StatusForbidden:
+		points = banScoreForbidden
+	}
+	if traversal {
+		points += banScoreTraversal
+	}
+	return points
+}
+
+// sweep 清理过期的计分和超过保留期的封禁记录，每个窗口最多执行一次，调用方需持有锁
+func (b *banList) sweep(now time.Time) {
+	if now.Sub(b.lastSweep) < b.config.Window {
+		return
+	}
+	b.lastSweep = now
+	for ip, score := range b.scores {
+		if now.Sub(score.windowStart) >= b.config.Window {
+			delete(b.scores, ip)
+		}
+	}
+	for ip, ban := range b.bans {
+		if now.Sub(ban.Until) > b.config.MaxDuration {
+			delete(b.bans, ip)
+		}
+	}
+}
+
+// list 返回当前生效的封禁，按结束时间排序
+func (b *banList) list() []Ban {
+	b.mu.Lock()
+	now := time.Now()
+	bans := make([]Ban, 0, len(b.bans))
+	for _, ban := range b.bans {
+		if now.Before(ban.Until) {
+			bans = append(bans, *ban)
+		}
+	}
+	b.mu.Unlock()
+
+	sort.Slice(bans, func(i, j int) bool { return bans[i].Until.Before(bans[j].Until) })
+	return bans
+}
+
+// unban 解除 IP 的封禁并清除其记录（之后再被封禁时从第一次的时长开始），ip 为空时清除全部
+// 返回解除的生效中的封禁数量
+func (b *banList) unban(ip string) int {
+	b.mu.Lock()
+	now := time.Now()
+	cleared := 0
+	for key, ban := range b.bans {
+		if ip != "" && key != ip {
+			continue
+		}
+		if now.Before(ban.Until) {
+			cleared++
+		}
+		delete(b.bans, key)
+		delete(b.scores, key)
+	}
+	b.mu.Unlock()
+
+	b.save()
+	return cleared
+}
+
+// banFile 持久化文件的格式
+type banFile struct {
+	Bans []Ban `json:"bans"`
+}
+
+func (b *banList) load() error {
+	if b.config.File == "" {
+		return nil
+	}
+	data, err := os.ReadFile(b.config.File)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var file banFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, ban := range file.Bans {
+		if ban.IP == "" || now.Sub(ban.Until) > b.config.MaxDuration {
+			continue
+		}
+		ban := ban
+		b.bans[ban.IP] = &ban
+	}
+	if len(b.bans) > 0 {
+		b.logger.Printf("[Ban] Loaded %d ban records from %s", len(b.bans), b.config.File)
+	}
+	return nil
+}
+
+// save 把封禁记录写入持久化文件
+func (b *banList) save() {
+	if b.config.File == "" {
+		return
+	}
+	b.saveMu.Lock()
+	defer b.saveMu.Unlock()
+
+	b.mu.Lock()
+	file := banFile{Bans: make([]Ban, 0, len(b.bans))}
+	for _, ban := range b.bans {
+		file.Bans = append(file.Bans, *ban)
+	}
+	b.mu.Unlock()
+	sort.Slice(file.Bans, func(i, j int) bool { return file.Bans[i].IP < file.Bans[j].IP })
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(b.config.File), 0755)
+	}
+	if err == nil {
+		err = writeFileAtomic(b.config.File, data)
+	}
+	if err != nil {
+		b.logger.Printf("[Ban] Failed to save %s: %v", b.config.File, err)
+	}
+}
+
+// isTraversalAttempt 判断请求路径（已解码）是否包含 .. 路径段，包括反斜杠分隔和二次编码的形式
+func isTraversalAttempt(urlPath string) bool {
+	for _, segment := range strings.FieldsFunc(urlPath, func(c rune) bool { return c == '/' || c == '\\' }) {
+		if segment == ".." {
+			return true
+		}
+	}
+	return strings.Contains(strings.ToLower(urlPath), "%2e%2e")
+}
@@ -0,0 +1,42 @@
+package hub
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestBanSkipsLoopbackAndTrustedProxies(t *testing.T) {
+	srv := newAPITestServer(t, "http://127.0.0.1:1", Options{
+		TrustedProxies: TrustedProxies{netip.MustParsePrefix("10.0.0.0/8")},
+		Ban:            BanConfig{Threshold: 3, Window: time.Minute, Duration: time.Minute, MaxDuration: time.Hour},
+	})
+
+	probe := func(remote string) int {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/wp-admin", nil)
+		req.RemoteAddr = remote
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for _, remote := range []string{"127.0.0.1:1234", "[::1]:1234", "10.1.2.3:1234"} {
+		for i := 0; i < 5; i++ {
+			if code := probe(remote); code != http.StatusNotFound {
+				t.Fatalf("%s request %d: status %d, want 404", remote, i, code)
+			}
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		probe("203.0.113.5:1234")
+	}
+	if code := probe("203.0.113.5:1234"); code != http.StatusForbidden {
+		t.Fatalf("untrusted client: status %d, want 403", code)
+	}
+	if bans := srv.bans.list(); len(bans) != 1 || bans[0].IP != "203.0.113.5" {
+		t.Fatalf("bans = %+v", bans)
+	}
+}
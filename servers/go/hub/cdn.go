@@ -0,0 +1,181 @@
+package hub
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 确保缓存目录存在
+func (s *Server) ensureCacheDir() error {
+	return os.MkdirAll(s.cdnCacheDir, 0755)
+}
+
+// 下载 CDN 文件到本地
+func (s *Server) downloadCDNFile(url, localPath string) (string, error) {
+	if err := s.ensureCacheDir(); err != nil {
+		return "", err
+	}
+
+	fullLocalPath := filepath.Join(s.cdnCacheDir, localPath)
+	localDir := filepath.Dir(fullLocalPath)
+
+	// 确保目标目录存在
+	if err := os.MkdirAll(localDir, 0755); err != nil {
+		return "", err
+	}
+
+	// 如果文件已存在，直接返回
+	if _, err := os.Stat(fullLocalPath); err == nil {
+		s.logger.Printf("CDN cache hit: %s", localPath)
+		return fullLocalPath, nil
+	}
+
+	s.logger.Printf("Downloading CDN file: %s", url)
+
+	// 发起 HTTP 请求
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	// 处理重定向
+	if resp.StatusCode == http.StatusMovedPermanently || resp.StatusCode == http.StatusFound {
+		location := resp.Header.Get("Location")
+		if location != "" {
+			return s.downloadCDNFile(location, localPath)
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download: %d", resp.StatusCode)
+	}
+
+	// 创建本地文件
+	file, err := os.Create(fullLocalPath)
+	if err != nil {
+		return "", err
+	}
+
+	// 写入文件内容
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		file.Close()
+		os.Remove(fullLocalPath)
+		return "", err
+	}
+	file.Close()
+
+	s.logger.Printf("Downloaded: %s", localPath)
+
+	// 生成预压缩文件
+	if err := precompressFile(fullLocalPath); err != nil {
+		s.logger.Printf("Failed to precompress %s: %v", localPath, err)
+	}
+	return fullLocalPath, nil
+}
+
+// 处理 CDN 代理请求
+// urlPath 为 /cdn/ 之后的部分，例如 npm/daisyui@4.12.24/dist/full.min.css 或 tailwindcss/tailwind.js
+func (s *Server) handleCDNProxy(w http.ResponseWriter, r *http.Request, urlPath string) {
+	if s.cdnCacheDir == "" && s.cdnFS == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if urlPath == "" || !fs.ValidPath(urlPath) {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	// 构建完整的 CDN URL
+	var cdnURL string
+	if strings.HasPrefix(urlPath, "tailwindcss/") {
+		// Tailwind CSS 使用特殊的 CDN
+		cdnURL = "https://cdn.tailwindcss.com"
+	} else {
+		// 其他使用 jsDelivr CDN
+		cdnURL = "https://cdn.jsdelivr.net/" + urlPath
+	}
+	localPath := filepath.FromSlash(urlPath)
+
+	// 尝试从缓存提供文件
+	if s.cdnCacheDir != "" {
+		cacheFS := os.DirFS(s.cdnCacheDir)
+		if _, err := fs.Stat(cacheFS, urlPath); err == nil {
+			s.sendCDNFile(w, r, cacheFS, urlPath)
+			return
+		}
+	}
+
+	// 尝试从预置的 CDN 缓存提供文件
+	if s.cdnFS != nil {
+		if _, err := fs.Stat(s.cdnFS, urlPath); err == nil {
+			s.sendCDNFile(w, r, s.cdnFS, urlPath)
+			return
+		}
+	}
+
+	// 未配置磁盘缓存目录时不下载
+	if s.cdnCacheDir == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	// 下载并提供文件
+	if _, err := s.downloadCDNFile(cdnURL, localPath); err != nil {
+		s.logger.Printf("CDN proxy error: %v", err)
+		http.Error(w, "Failed to fetch from CDN", http.StatusBadGateway)
+		return
+	}
+	s.sendCDNFile(w, r, os.DirFS(s.cdnCacheDir), urlPath)
+}
+
+// PrewarmCDN 预下载常用 CDN 文件，未配置 CDNCacheDir 时不做任何事
+func (s *Server) PrewarmCDN() {
+	if s.cdnCacheDir == "" {
+		return
+	}
+
+	commonFiles := []struct {
+		url  string
+		path string
+	}{
+		{
+			url:  "https://cdn.jsdelivr.net/npm/daisyui@4.12.24/dist/full.min.css",
+			path: filepath.FromSlash("npm/daisyui@4.12.24/dist/full.min.css"),
+		},
+		{
+			url:  "https://cdn.jsdelivr.net/npm/alpinejs@3.13.3/dist/cdn.min.js",
+			path: filepath.FromSlash("npm/alpinejs@3.13.3/dist/cdn.min.js"),
+		},
+		{
+			url:  "https://cdn.jsdelivr.net/npm/crypto-js@4.2.0/crypto-js.min.js",
+			path: filepath.FromSlash("npm/crypto-js@4.2.0/crypto-js.min.js"),
+		},
+	}
+
+	s.logger.Println("Prewarming CDN cache...")
+	for _, file := range commonFiles {
+		// 已预置的文件无需下载
+		if s.cdnFS != nil {
+			if _, err := fs.Stat(s.cdnFS, filepath.ToSlash(file.path)); err == nil {
+				continue
+			}
+		}
+		if _, err := s.downloadCDNFile(file.url, file.path); err != nil {
+			s.logger.Printf("Failed to prewarm %s: %v", file.path, err)
+		}
+	}
+	s.logger.Println("CDN cache prewarm complete")
+}
+
+// 发送 CDN 文件（带版本号的 CDN 文件可以长期缓存）
+func (s *Server) sendCDNFile(w http.ResponseWriter, r *http.Request, fsys fs.FS, name string) {
+	w.Header().Set("Cache-Control", "public, max-age=31536000")
+	s.serveFile(w, r, fsys, name)
+}
@@ -0,0 +1,211 @@
+package hub
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// 小于该大小的响应不压缩
+const minCompressSize = 1024
+
+// compressWriter 边写边压缩的 ResponseWriter
+// 先缓冲最多 minCompressSize 字节，确定状态码、Content-Type 和响应大小后决定是否压缩；
+// 决定后直接流式写出，不会缓冲整个响应。以下情况原样透传：
+//   - 状态码不是 200（304、206 等）
+//   - 处理函数已经设置了 Content-Encoding（预压缩的页面缓存和静态文件）
+//   - Content-Type 不在 compressibleTypes 中
+//   - 响应小于 minCompressSize，或客户端不接受任何支持的编码
+type compressWriter struct {
+	http.ResponseWriter
+	r *http.Request
+
+	status  int
+	buf     []byte
+	decided bool
+
+	encoding string
+	c        compressor
+}
+
+func newCompressWriter(w http.ResponseWriter, r *http.Request) *compressWriter {
+	return &compressWriter{ResponseWriter: w, r: r}
+}
+
+// Unwrap 供 http.ResponseController 访问底层 ResponseWriter
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.status != 0 {
+		return
+	}
+	// 1xx 信息响应直接透传
+	if status >= 100 && status < 200 {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	cw.status = status
+
+	// 不满足压缩条件时立即写出响应头，之后直接透传
+	if cw.encodingFor(-1) == "" {
+		cw.start("")
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.decided {
+		if cw.c != nil {
+			return cw.c.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= minCompressSize {
+		if err := cw.start(cw.encodingFor(len(cw.buf))); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush 立即写出已缓冲的内容（流式响应需要）
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if cw.status == 0 {
+			cw.WriteHeader(http.StatusOK)
+		}
+		if !cw.decided {
+			cw.start(cw.encodingFor(len(cw.buf)))
+		}
+	}
+	if cw.c != nil {
+		cw.c.Flush()
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close 写出剩余内容和压缩尾部，并归还压缩写入器
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if cw.status == 0 {
+			// 处理函数没有写任何内容，交给 net/http 使用默认的 200
+			if len(cw.buf) == 0 {
+				cw.decided = true
+				return nil
+			}
+			cw.status = http.StatusOK
+		}
+		// HEAD 请求没有响应体，按 Content-Length 判断，保证响应头与 GET 一致
+		size := len(cw.buf)
+		if cw.r.Method == http.MethodHead {
+			size = cw.contentLength()
+		}
+		if err := cw.start(cw.encodingFor(size)); err != nil {
+			return err
+		}
+	}
+	if cw.c == nil {
+		return nil
+	}
+	err := cw.c.Close()
+	putCompressor(cw.encoding, cw.c)
+	cw.c = nil
+	return err
+}
+
+// encodingFor 返回应使用的编码，"" 表示不压缩
+// size 为已知的响应大小，-1 表示尚未确定（只检查与大小无关的条件）
+func (cw *compressWriter) encodingFor(size int) string {
+	header := cw.Header()
+	if cw.status != http.StatusOK || header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return ""
+	}
+
+	contentType := header.Get("Content-Type")
+	if contentType == "" && len(cw.buf) > 0 {
+		// 与 net/http 相同的内容嗅探，写出前固定下来
+		contentType = http.DetectContentType(cw.buf)
+		header.Set("Content-Type", contentType)
+	}
+	if contentType != "" && !isCompressible(contentType) {
+		return ""
+	}
+	if length := cw.contentLength(); length >= 0 && length < minCompressSize {
+		return ""
+	}
+	if size >= 0 && size < minCompressSize {
+		return ""
+	}
+
+	names := make([]string, len(contentEncodings))
+	for i, enc := range contentEncodings {
+		names[i] = enc.name
+	}
+	return negotiateEncoding(cw.r.Header.Get("Accept-Encoding"), names)
+}
+
+// contentLength 处理函数设置的 Content-Length，未设置时返回 -1
+func (cw *compressWriter) contentLength() int {
+	length, err := strconv.Atoi(cw.Header().Get("Content-Length"))
+	if err != nil {
+		return -1
+	}
+	return length
+}
+
+// start 写出响应头和已缓冲的内容，encoding 为空表示不压缩
+func (cw *compressWriter) start(encoding string) error {
+	cw.decided = true
+	header := cw.Header()
+
+	if isCompressible(header.Get("Content-Type")) && header.Get("Content-Encoding") == "" {
+		addVary(header, "Accept-Encoding")
+	}
+
+	if encoding != "" {
+		cw.encoding = encoding
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", encoding)
+		// 压缩后是不同的表示，强 ETag 需要区分
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") && !strings.HasSuffix(etag, "-"+encoding+`"`) {
+			header.Set("ETag", strings.TrimSuffix(etag, `"`)+"-"+encoding+`"`)
+		}
+		if cw.r.Method != http.MethodHead {
+			cw.c = getCompressor(encoding, cw.ResponseWriter)
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	buf := cw.buf
+	cw.buf = nil
+	var err error
+	if cw.c != nil {
+		_, err = cw.c.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// addVary 向 Vary 响应头添加字段（已存在时不重复添加）
+func addVary(header http.Header, field string) {
+	for _, value := range header.Values("Vary") {
+		for _, existing := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(existing), field) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", field)
+}
@@ -0,0 +1,228 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConcurrencyConfig 并发限制和过载保护
+// 超过并发数的请求进入有界队列等待，队列已满、预计等待时间超过期限或等待超时时返回 503；
+// 健康检查和管理接口使用单独的优先通道，不和普通请求排队；
+// 长时间占用连接的响应（状态推送和被限速写出的响应）使用单独的流式通道，不占用页面渲染的位置
+type ConcurrencyConfig struct {
+	MaxInFlight        int           // 全局同时处理的请求数，0 表示不限制
+	MaxInFlightPerSite int           // 每个站点同时处理的请求数，0 表示不限制
+	MaxQueue           int           // 每个通道最多等待的请求数，0 表示不排队
+	QueueTimeout       time.Duration // 最长等待时间，0 表示 1 秒；请求本身的截止时间更早时以其为准
+	PriorityInFlight   int           // 健康检查和管理接口的并发数，0 表示 8
+	StreamInFlight     int           // 持续很久的响应（状态推送、限速写出，有总带宽上限时的静态文件、CDN 文件和导出）的并发数，0 表示 64
+}
+
+// Validate 检查配置是否可用
+func (c ConcurrencyConfig) Validate() error {
+	if c.MaxInFlight < 0 || c.MaxInFlightPerSite < 0 || c.MaxQueue < 0 || c.QueueTimeout < 0 || c.PriorityInFlight < 0 || c.StreamInFlight < 0 {
+		return fmt.Errorf("max_in_flight, max_in_flight_per_site, max_queue, queue_timeout, priority_in_flight and stream_in_flight must not be negative")
+	}
+	return nil
+}
+
+// lane 一个并发通道：信号量加有界等待队列
+type lane struct {
+	slots    chan struct{}
+	maxQueue int
+	waiting  atomic.Int64
+	avgNanos atomic.Int64 // 请求处理时间的指数移动平均，用于估算排队时间
+}
+
+func newLane(size, maxQueue int) *lane {
+	return &lane{slots: make(chan struct{}, size), maxQueue: maxQueue}
+}
+
+// acquire 在 deadline 之前取得一个位置，失败时返回建议的重试等待时间
+func (l *lane) acquire(ctx context.Context, deadline time.Time) (bool, time.Duration) {
+	select {
+	case l.slots <- struct{}{}:
+		return true, 0
+	default:
+	}
+
+	// 队列已满，或按平均处理时间估算排到时已超过期限：直接拒绝，不占用队列
+	waiting := l.waiting.Add(1)
+	defer l.waiting.Add(-1)
+	estimate := l.estimatedWait(int(waiting))
+	if int(waiting) > l.maxQueue || time.Now().Add(estimate).After(deadline) {
+		return false, estimate
+	}
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return true, 0
+	case <-timer.C:
+		return false, estimate
+	case <-ctx.Done():
+		return false, estimate
+	}
+}
+
+// release 归还位置并记录处理时间
+func (l *lane) release(elapsed time.Duration) {
+	<-l.slots
+	for {
+		avg := l.avgNanos.Load()
+		next := int64(elapsed)
+		if avg > 0 {
+			next = avg + (int64(elapsed)-avg)/8
+		}
+		if l.avgNanos.CompareAndSwap(avg, next) {
+			return
+		}
+	}
+}
+
+// cancel 归还没有用于处理请求的位置（后续通道排队失败），不计入处理时间
+func (l *lane) cancel() {
+	<-l.slots
+}
+
+// estimatedWait 估算排在第 position 位的请求需要等待的时间
+func (l *lane) estimatedWait(position int) time.Duration {
+	return time.Duration(l.avgNanos.Load() * int64(position) / int64(cap(l.slots)))
+}
+
+// inFlight 正在处理的请求数
+func (l *lane) inFlight() int {
+	return len(l.slots)
+}
+
+// concurrencyLimiter 按通道限制并发
+type concurrencyLimiter struct {
+	config   ConcurrencyConfig
+	logger   *log.Logger
+	global   *lane // 为 nil 表示不限制
+	priority *lane
+	stream   *lane
+
+	mu    sync.Mutex
+	sites map[string]*lane
+
+	shed    atomic.Int64 // 上次日志以来拒绝的请求数
+	lastLog atomic.Int64 // 上次记录拒绝日志的时间（UnixNano）
+}
+
+func newConcurrencyLimiter(config ConcurrencyConfig, logger *log.Logger) *concurrencyLimiter {
+	if config.QueueTimeout <= 0 {
+		config.QueueTimeout = time.Second
+	}
+	if config.PriorityInFlight <= 0 {
+		config.PriorityInFlight = 8
+	}
+	if config.StreamInFlight <= 0 {
+		config.StreamInFlight = 64
+	}
+	c := &concurrencyLimiter{
+		config:   config,
+		logger:   logger,
+		priority: newLane(config.PriorityInFlight, config.MaxQueue),
+		stream:   newLane(config.StreamInFlight, config.MaxQueue),
+		sites:    make(map[string]*lane),
+	}
+	if config.MaxInFlight > 0 {
+		c.global = newLane(config.MaxInFlight, config.MaxQueue)
+	}
+	return c
+}
+
+// acquire 为请求取得处理位置，成功时返回的 release 必须在请求结束时调用；
+// 失败时返回建议的 Retry-After
+// 健康检查和管理接口只使用优先通道，流式响应（stream）只使用流式通道；
+// 其他请求先取站点通道再取全局通道，共用同一个期限
+func (c *concurrencyLimiter) acquire(ctx context.Context, site, route string, stream bool) (func(), time.Duration, bool) {
+	deadline := time.Now().Add(c.config.QueueTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	var lanes []*lane
+	switch {
+	case route == RouteHealth || route == RouteAdmin:
+		lanes = append(lanes, c.priority)
+	case stream:
+		lanes = append(lanes, c.stream)
+	default:
+		if site != "" && c.config.MaxInFlightPerSite > 0 {
+			lanes = append(lanes, c.siteLane(site))
+		}
+		if c.global != nil {
+			lanes = append(lanes, c.global)
+		}
+	}
+
+	for i, l := range lanes {
+		if ok, retryAfter := l.acquire(ctx, deadline); !ok {
+			for _, acquired := range lanes[:i] {
+				acquired.cancel()
+			}
+			c.recordShed(site, route)
+			return nil, max(retryAfter, time.Second), false
+		}
+	}
+
+	start := time.Now()
+	return func() {
+		elapsed := time.Since(start)
+		for _, l := range lanes {
+			l.release(elapsed)
+		}
+	}, 0, true
+}
+
+// siteLane 取出（或创建）站点的通道
+func (c *concurrencyLimiter) siteLane(site string) *lane {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	l, ok := c.sites[site]
+	if !ok {
+		l = newLane(c.config.MaxInFlightPerSite, c.config.MaxQueue)
+		c.sites[site] = l
+	}
+	return l
+}
+
+// recordShed 统计被拒绝的请求，每 10 秒最多记录一条日志
+func (c *concurrencyLimiter) recordShed(site, route string) {
+	shed := c.shed.Add(1)
+	now := time.Now().UnixNano()
+	last := c.lastLog.Load()
+	if now-last < int64(10*time.Second) || !c.lastLog.CompareAndSwap(last, now) {
+		return
+	}
+	c.shed.Add(-shed)
+	c.logger.Printf("[Overload] Shed %d requests (last: site %q, route %s)", shed, site, route)
+}
+
+// stats 当前的并发和排队情况，用于健康检查
+func (c *concurrencyLimiter) stats() map[string]interface{} {
+	stats := map[string]interface{}{}
+	if c.global != nil {
+		stats["in_flight"] = c.global.inFlight()
+		stats["queued"] = c.global.waiting.Load()
+	}
+	stats["streams"] = c.stream.inFlight()
+	c.mu.Lock()
+	if len(c.sites) > 0 {
+		sites := make(map[string]interface{}, len(c.sites))
+		for name, l := range c.sites {
+			sites[name] = map[string]interface{}{"in_flight": l.inFlight(), "queued": l.waiting.Load()}
+		}
+		stats["sites"] = sites
+	}
+	c.mu.Unlock()
+	return stats
+}
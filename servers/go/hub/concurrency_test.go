@@ -0,0 +1,77 @@
+package hub
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyStreamLane(t *testing.T) {
+	c := newConcurrencyLimiter(ConcurrencyConfig{MaxInFlight: 1, StreamInFlight: 1, QueueTimeout: 10 * time.Millisecond}, log.New(io.Discard, "", 0))
+	ctx := context.Background()
+
+	// 流式响应占满流式通道后，普通请求仍能取得全局位置
+	releaseStream, _, ok := c.acquire(ctx, "aliyun", RouteAPI, true)
+	if !ok {
+		t.Fatal("stream: not acquired")
+	}
+	if _, _, ok := c.acquire(ctx, "aliyun", RouteAPI, true); ok {
+		t.Fatal("second stream acquired beyond stream_in_flight")
+	}
+	releasePage, _, ok := c.acquire(ctx, "aliyun", RoutePage, false)
+	if !ok {
+		t.Fatal("page request blocked by stream")
+	}
+	if _, _, ok := c.acquire(ctx, "aliyun", RoutePage, false); ok {
+		t.Fatal("second page request acquired beyond max_in_flight")
+	}
+	releasePage()
+	releaseStream()
+
+	if _, _, ok := c.acquire(ctx, "aliyun", RouteAPI, true); !ok {
+		t.Fatal("stream slot not released")
+	}
+}
+
+func TestIsLongResponse(t *testing.T) {
+	tests := []struct {
+		route, path string
+		throttle    int64
+		unshaped    bool
+		shaped      bool
+	}{
+		{RoutePage, "/aliyun/", 0, false, false},
+		{RoutePage, "/aliyun/", 1024, true, true},
+		{RouteAPI, "/aliyun/api/status/ecs_instances", 0, true, true},
+		{RouteAPI, "/aliyun/api/export/ecs_instances", 0, false, true},
+		{RouteAPI, "/aliyun/api/proxy/ecs/DescribeInstances", 0, false, false},
+		{RouteStatic, "/aliyun/static/app.js", 0, false, true},
+		{RouteCDN, "/cdn/npm/vue@3/dist/vue.global.js", 0, false, true},
+	}
+	unshaped := &Server{shaper: newEgressShaper(0)}
+	shaped := &Server{shaper: newEgressShaper(1 << 20)}
+	for _, tt := range tests {
+		if got := unshaped.isLongResponse(tt.route, tt.path, tt.throttle); got != tt.unshaped {
+			t.Errorf("without egress_rate: isLongResponse(%s, %s, %d) = %v, want %v", tt.route, tt.path, tt.throttle, got, tt.unshaped)
+		}
+		if got := shaped.isLongResponse(tt.route, tt.path, tt.throttle); got != tt.shaped {
+			t.Errorf("with egress_rate: isLongResponse(%s, %s, %d) = %v, want %v", tt.route, tt.path, tt.throttle, got, tt.shaped)
+		}
+	}
+}
+
+func TestIsStatusStreamPath(t *testing.T) {
+	for path, want := range map[string]bool{
+		"/aliyun/api/status/ecs_instances": true,
+		"/api/status/ecs_instances":        true,
+		"/aliyun/api/proxy/ecs/Describe":   false,
+		"/aliyun/api/status":               false,
+		"/aliyun/api/status/a/b":           false,
+	} {
+		if got := isStatusStreamPath(path); got != want {
+			t.Errorf("isStatusStreamPath(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
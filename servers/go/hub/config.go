@@ -0,0 +1,87 @@
+package hub
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+)
+
+// SiteInfo sites.json 中单个站点的信息
+type SiteInfo struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Icon        string   `json:"icon"`
+	Path        string   `json:"path"`
+	Enabled     bool     `json:"enabled"`
+	Category    string   `json:"category"`
+	Domains     []string `json:"domains"`
+	Order       int      `json:"order"`
+}
+
+// PlatformInfo 平台信息
+type PlatformInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+// SitesConfig sites.json 的结构
+type SitesConfig struct {
+	Platform      PlatformInfo        `json:"platform"`
+	Sites         map[string]SiteInfo `json:"sites"`
+	HomeSite      string              `json:"home_site"`
+	DomainMapping map[string]string   `json:"domain_mapping"`
+}
+
+// Config 站点配置
+// 不预设任何结构，config.json 中的所有字段原样传递给模板和 /api/config，
+// 只有 pages 字段会被额外解析用于页面查找和导航排序
+type Config map[string]interface{}
+
+// Pages 返回 pages 字段中的页面配置
+func (c Config) Pages() map[string]map[string]interface{} {
+	pages := make(map[string]map[string]interface{})
+	pagesMap, ok := c["pages"].(map[string]interface{})
+	if !ok {
+		return pages
+	}
+	for key, val := range pagesMap {
+		if pageMap, ok := val.(map[string]interface{}); ok {
+			pages[key] = pageMap
+		}
+	}
+	return pages
+}
+
+func loadSitesConfig(fsys fs.FS) (SitesConfig, error) {
+	var sitesConfig SitesConfig
+	file, err := fs.ReadFile(fsys, "sites.json")
+	if err != nil {
+		return sitesConfig, err
+	}
+	err = json.Unmarshal(file, &sitesConfig)
+	return sitesConfig, err
+}
+
+func loadSiteConfig(fsys fs.FS, siteName string) (Config, error) {
+	configPath := path.Join(siteName, "config.json")
+	file, err := fs.ReadFile(fsys, configPath)
+	if err != nil {
+		return nil, err
+	}
+	var config Config
+	if err := json.Unmarshal(file, &config); err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = Config{}
+	}
+	// pages 必须是对象，否则无法进行页面查找
+	if pages, exists := config["pages"]; exists {
+		if _, ok := pages.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("%s: \"pages\" must be an object", configPath)
+		}
+	}
+	return config, nil
+}
@@ -0,0 +1,117 @@
+package hub
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Credential 云服务 API 的访问凭证，只保存在服务端
+type Credential struct {
+	AccessKeyID     string `json:"access_key_id"`
+	AccessKeySecret string `json:"access_key_secret"`
+	SecurityToken   string `json:"security_token,omitempty"` // STS 临时凭证的令牌
+
+	// Name 凭证名称（保险库中的名称，或凭证文件中的站点键），由 CredentialStore 填写，用于策略和审计日志
+	Name string `json:"-"`
+}
+
+// String 打码显示，防止凭证通过日志或 %v 泄露
+func (c Credential) String() string {
+	return "Credential{" + maskAccessKeyID(c.AccessKeyID) + "}"
+}
+
+// GoString 与 String 相同，覆盖 %#v
+func (c Credential) GoString() string {
+	return c.String()
+}
+
+// MarshalJSON 只输出打码的 AccessKey ID，凭证不会被编码进接口响应或模板
+func (c Credential) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{"access_key_id": maskAccessKeyID(c.AccessKeyID)})
+}
+
+// maskAccessKeyID 只保留前后各 4 个字符
+func maskAccessKeyID(id string) string {
+	if len(id) <= 8 {
+		return "****"
+	}
+	return id[:4] + "****" + id[len(id)-4:]
+}
+
+// CredentialStore 按站点提供服务端保存的凭证，用于 /{site}/api/proxy/ 代理签名
+type CredentialStore interface {
+	// Credential 返回站点使用的凭证，没有配置时返回 false
+	Credential(site string) (Credential, bool)
+}
+
+// StaticCredentials 固定的凭证表，键为站点名，"*" 为所有站点的默认凭证
+type StaticCredentials map[string]Credential
+
+func (c StaticCredentials) Credential(site string) (Credential, bool) {
+	if credential, ok := c[site]; ok {
+		credential.Name = site
+		return credential, true
+	}
+	credential, ok := c["*"]
+	credential.Name = "*"
+	return credential, ok
+}
+
+// LoadCredentialsFile 从 JSON 文件加载凭证表：
+//
+//	{"aliyun": {"access_key_id": "LTAI...", "access_key_secret": "..."}}
+func LoadCredentialsFile(filePath string) (StaticCredentials, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var credentials StaticCredentials
+	if err := json.Unmarshal(data, &credentials); err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	for site, credential := range credentials {
+		if credential.AccessKeyID == "" || credential.AccessKeySecret == "" {
+			return nil, fmt.Errorf("%s: %s: access_key_id and access_key_secret are required", filePath, site)
+		}
+	}
+	return credentials, nil
+}
+
+// CredentialSource 按名称提供凭证，Vault 实现了该接口
+type CredentialSource interface {
+	Lookup(name string) (Credential, bool)
+}
+
+// SiteCredentials 站点通过名称引用凭证的 CredentialStore
+// Sites 的键为站点名，"*" 为所有站点的默认，值为 Source 中的凭证名称
+type SiteCredentials struct {
+	Source CredentialSource
+	Sites  map[string]string
+}
+
+func (c SiteCredentials) Credential(site string) (Credential, bool) {
+	name, ok := c.Sites[site]
+	if !ok {
+		name, ok = c.Sites["*"]
+	}
+	if !ok {
+		return Credential{}, false
+	}
+	credential, ok := c.Source.Lookup(name)
+	credential.Name = name
+	return credential, ok
+}
+
+// Reload 凭证来源支持重新加载时（例如 Vault）重新读取，轮换凭证后无需重启
+func (c SiteCredentials) Reload() error {
+	if reloader, ok := c.Source.(credentialReloader); ok {
+		return reloader.Reload()
+	}
+	return nil
+}
+
+// credentialReloader 可以重新加载的凭证存储，Server.Reload 时一并调用
+type credentialReloader interface {
+	Reload() error
+}
@@ -0,0 +1,157 @@
+package hub
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressor 可复用的压缩写入器（gzip.Writer、brotli.Writer、zstd.Encoder 都满足）
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// contentEncoding 一种 Content-Encoding 及其压缩文件扩展名
+type contentEncoding struct {
+	name string
+	ext  string
+	pool *sync.Pool        // 实时压缩使用的写入器（速度优先）
+	best func() compressor // 生成预压缩文件使用的写入器（压缩率优先）
+}
+
+// 服务端支持的编码，按偏好排序（客户端 q 值相同时靠前的优先）
+var contentEncodings = []contentEncoding{
+	{
+		name: "br",
+		ext:  ".br",
+		pool: &sync.Pool{New: func() any { return brotli.NewWriterLevel(nil, 5) }},
+		best: func() compressor { return brotli.NewWriterLevel(nil, brotli.BestCompression) },
+	},
+	{
+		name: "zstd",
+		ext:  ".zst",
+		pool: &sync.Pool{New: func() any { return newZstdWriter(zstd.SpeedDefault) }},
+		best: func() compressor { return newZstdWriter(zstd.SpeedBestCompression) },
+	},
+	{
+		name: "gzip",
+		ext:  ".gz",
+		pool: &sync.Pool{New: func() any { return gzip.NewWriter(nil) }},
+		best: func() compressor {
+			w, _ := gzip.NewWriterLevel(nil, gzip.BestCompression)
+			return w
+		},
+	},
+}
+
+// newZstdWriter 创建适合 HTTP 的 zstd 写入器
+// 浏览器最多只接受 8MB 的窗口，单线程编码避免每个响应启动额外的 goroutine
+func newZstdWriter(level zstd.EncoderLevel) compressor {
+	w, _ := zstd.NewWriter(nil,
+		zstd.WithEncoderLevel(level),
+		zstd.WithEncoderConcurrency(1),
+		zstd.WithWindowSize(1<<20),
+	)
+	return w
+}
+
+// findEncoding 按名称查找编码
+func findEncoding(name string) *contentEncoding {
+	for i := range contentEncodings {
+		if contentEncodings[i].name == name {
+			return &contentEncodings[i]
+		}
+	}
+	return nil
+}
+
+// getCompressor 从池中取出写入器并指向 w，用完后调用 putCompressor 归还
+func getCompressor(name string, w io.Writer) compressor {
+	enc := findEncoding(name)
+	if enc == nil {
+		return nil
+	}
+	c := enc.pool.Get().(compressor)
+	c.Reset(w)
+	return c
+}
+
+// putCompressor 归还写入器（调用前应已 Close）
+func putCompressor(name string, c compressor) {
+	if enc := findEncoding(name); enc != nil {
+		c.Reset(nil)
+		enc.pool.Put(c)
+	}
+}
+
+// compressBytes 使用池中的写入器压缩 data
+func compressBytes(name string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	c := getCompressor(name, &buf)
+	defer putCompressor(name, c)
+	if _, err := c.Write(data); err != nil {
+		return nil, err
+	}
+	if err := c.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// negotiateEncoding 根据 Accept-Encoding 从 offered 中选择编码，返回 "" 表示不压缩
+// 支持 q 值和 *，q=0 表示拒绝；q 值相同时按 offered 的顺序（服务端偏好）选择；
+// 客户端显式给 identity 更高的 q 值时不压缩
+func negotiateEncoding(acceptEncoding string, offered []string) string {
+	if acceptEncoding == "" || len(offered) == 0 {
+		return ""
+	}
+
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if name == "x-gzip" {
+			name = "gzip"
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(strings.TrimSpace(key), "q") {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		qualities[name] = q
+	}
+
+	quality := func(name string) (float64, bool) {
+		if q, ok := qualities[name]; ok {
+			return q, true
+		}
+		q, ok := qualities["*"]
+		return q, ok
+	}
+
+	best, bestQ := "", 0.0
+	for _, name := range offered {
+		if q, ok := quality(name); ok && q > bestQ {
+			best, bestQ = name, q
+		}
+	}
+	if identityQ, ok := quality("identity"); ok && identityQ > bestQ {
+		return ""
+	}
+	return best
+}
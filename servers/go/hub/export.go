@@ -0,0 +1,368 @@
+package hub
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// tableColumn 站点配置 tables.{table}.fields 中 showInTable 的一列
+type tableColumn struct {
+	Label        string
+	Field        string // 字段路径，支持数组下标，例如 VpcAttributes.PrivateIpAddress.IpAddress[0]
+	DefaultValue string // 值为空时显示的文本，默认 -
+	Suffix       string // 非空值后面追加的单位，例如 GB
+}
+
+// tableColumnsFor 表格在页面中显示的列（操作列除外），与前端 getColumnValue 的处理一致
+func tableColumnsFor(config Config, table string) ([]tableColumn, bool) {
+	tables, _ := config["tables"].(map[string]interface{})
+	entry, ok := tables[table].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	fields, _ := entry["fields"].([]interface{})
+	var columns []tableColumn
+	for _, item := range fields {
+		field, _ := item.(map[string]interface{})
+		if show, _ := field["showInTable"].(bool); !show || field["columnType"] == "actions" {
+			continue
+		}
+		column := tableColumn{DefaultValue: "-"}
+		column.Label, _ = field["label"].(string)
+		column.Field, _ = field["field"].(string)
+		column.Suffix, _ = field["suffix"].(string)
+		if value, _ := field["defaultValue"].(string); value != "" {
+			column.DefaultValue = value
+		}
+		if column.Field != "" {
+			columns = append(columns, column)
+		}
+	}
+	return columns, true
+}
+
+// text 行中该列显示的文本
+func (c tableColumn) text(row map[string]interface{}) string {
+	value := lookupFieldPath(row, c.Field)
+	text := cellText(value, c.DefaultValue)
+	if c.Suffix != "" && text != c.DefaultValue {
+		text += c.Suffix
+	}
+	return text
+}
+
+// value 行中该列的值：没有单位的数字和布尔值保持原类型，其余为 text
+func (c tableColumn) value(row map[string]interface{}) interface{} {
+	switch value := lookupFieldPath(row, c.Field).(type) {
+	case json.Number:
+		if c.Suffix == "" {
+			return value
+		}
+	case bool:
+		return value
+	}
+	return c.text(row)
+}
+
+// lookupFieldPath 按表格字段的路径读取行中的值，路径段可以带数组下标，例如 PublicIpAddress.IpAddress[0]
+func lookupFieldPath(row map[string]interface{}, path string) interface{} {
+	var value interface{} = row
+	for _, key := range strings.Split(path, ".") {
+		key, index, hasIndex := strings.Cut(key, "[")
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = fields[key]
+		if hasIndex {
+			n, err := strconv.Atoi(strings.TrimSuffix(index, "]"))
+			list, ok := value.([]interface{})
+			if err != nil || !ok || n < 0 || n >= len(list) {
+				return nil
+			}
+			value = list[n]
+		}
+	}
+	return value
+}
+
+// cellText 把 JSON 值转换为显示的文本，空值使用 defaultValue，对象和数组显示为 JSON
+func cellText(value interface{}, defaultValue string) string {
+	switch v := value.(type) {
+	case nil:
+		return defaultValue
+	case string:
+		if v == "" {
+			return defaultValue
+		}
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return defaultValue
+	}
+	return string(data)
+}
+
+// exportFormat 导出格式
+type exportFormat struct {
+	ContentType string
+	Extension   string
+	Write       func(w io.Writer, sheet string, columns []tableColumn, rows []map[string]interface{}) error
+}
+
+var exportFormats = map[string]exportFormat{
+	"csv":    {"text/csv; charset=utf-8", "csv", writeExportCSV},
+	"xlsx":   {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "xlsx", writeExportXLSX},
+	"ndjson": {"application/x-ndjson", "ndjson", writeExportNDJSON},
+}
+
+// writeExportCSV 以表头为第一行写出 CSV，带 UTF-8 BOM 以便 Excel 正确识别中文
+func writeExportCSV(w io.Writer, sheet string, columns []tableColumn, rows []map[string]interface{}) error {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	record := make([]string, len(columns))
+	for i, column := range columns {
+		record[i] = column.Label
+	}
+	writer.Write(record)
+	for _, row := range rows {
+		for i, column := range columns {
+			value := column.value(row)
+			if text, ok := value.(string); ok {
+				record[i] = csvSafeText(text)
+			} else {
+				record[i] = cellText(value, column.DefaultValue)
+			}
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvSafeText 以 = + - @ 开头的文本在电子表格中会被当作公式，前面加单引号
+func csvSafeText(text string) string {
+	if len(text) > 1 && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+// writeExportXLSX 写出只有一个工作表的 XLSX，表头加粗并冻结；数字写为数值单元格
+func writeExportXLSX(w io.Writer, sheet string, columns []tableColumn, rows []map[string]interface{}) error {
+	file := excelize.NewFile()
+	defer file.Close()
+	if file.SetSheetName("Sheet1", sheet) != nil {
+		sheet = "Sheet1"
+	}
+	stream, err := file.NewStreamWriter(sheet)
+	if err != nil {
+		return err
+	}
+	bold, err := file.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+	if err := stream.SetPanes(&excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return err
+	}
+
+	header := make([]interface{}, len(columns))
+	for i, column := range columns {
+		header[i] = excelize.Cell{StyleID: bold, Value: column.Label}
+	}
+	if err := stream.SetRow("A1", header); err != nil {
+		return err
+	}
+	for n, row := range rows {
+		cells := make([]interface{}, len(columns))
+		for i, column := range columns {
+			cells[i] = xlsxValue(column.value(row))
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, n+2)
+		if err := stream.SetRow(cell, cells); err != nil {
+			return err
+		}
+	}
+	if err := stream.Flush(); err != nil {
+		return err
+	}
+	_, err = file.WriteTo(w)
+	return err
+}
+
+// xlsxValue Excel 的数值只有 15 位有效数字，更长的数字（例如 ID）写为文本
+func xlsxValue(value interface{}) interface{} {
+	number, ok := value.(json.Number)
+	if !ok {
+		return value
+	}
+	if f, err := number.Float64(); err == nil && len(strings.TrimLeft(number.String(), "-")) <= 15 {
+		return f
+	}
+	return number.String()
+}
+
+// writeExportNDJSON 每行一个 JSON 对象，键为表头，顺序与列一致
+func writeExportNDJSON(w io.Writer, sheet string, columns []tableColumn, rows []map[string]interface{}) error {
+	var line bytes.Buffer
+	for _, row := range rows {
+		line.Reset()
+		line.WriteByte('{')
+		for i, column := range columns {
+			if i > 0 {
+				line.WriteByte(',')
+			}
+			key, _ := json.Marshal(column.Label)
+			value, err := json.Marshal(column.value(row))
+			if err != nil {
+				return err
+			}
+			line.Write(key)
+			line.WriteByte(':')
+			line.Write(value)
+		}
+		line.WriteString("}\n")
+		if _, err := w.Write(line.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleAPIExport 处理 /{site}/api/export/{table}?format=csv|xlsx|ndjson（默认 csv）：
+// 按表格 fields 中 showInTable 的列导出，表头为 label，值按 field 路径读取并应用 defaultValue 和 suffix；
+// 行中带有所属地域时第一列为地域。
+//
+//	POST  请求体为 JSON 数组，导出浏览器已经加载的行，不使用服务端凭证，不需要登录
+//	GET   以服务端凭证跨地域查询后导出，登录和参数与 /{site}/api/fanout/{table} 相同；部分地域失败时在 X-Incomplete-Regions 中列出
+func (s *Server) handleAPIExport(w http.ResponseWriter, r *http.Request, state *siteState, siteName, origin, tableName string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		writeAPIProxyError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "Only GET and POST are allowed")
+		return
+	}
+	query := r.URL.Query()
+	formatName := query.Get("format")
+	if formatName == "" {
+		formatName = "csv"
+	}
+	format, ok := exportFormats[formatName]
+	if !ok {
+		writeAPIProxyError(w, http.StatusBadRequest, "InvalidFormat", "format must be csv, xlsx or ndjson")
+		return
+	}
+	config := state.siteConfigs[siteName]
+	columns, ok := tableColumnsFor(config, tableName)
+	if !ok {
+		writeAPIProxyError(w, http.StatusNotFound, "UnknownTable", "Table is not configured: "+tableName)
+		return
+	}
+
+	var rows []map[string]interface{}
+	if r.Method == http.MethodPost {
+		var items []interface{}
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber()
+		if err := decoder.Decode(&items); err != nil {
+			writeAPIProxyError(w, http.StatusBadRequest, "InvalidParameter", "Request body must be a JSON array of rows")
+			return
+		}
+		rows = exportRows(items)
+	} else {
+		if s.credentials == nil {
+			http.NotFound(w, r)
+			return
+		}
+		credential, ok := s.credentials.Credential(siteName)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if _, ok := s.authenticateAPI(w, r, siteName, origin); !ok {
+			return
+		}
+		job, apiErr := s.newFanOutJob(config, siteName, credential, tableName)
+		if apiErr != nil {
+			writeAPIProxyError(w, apiErr.Status, apiErr.Code, apiErr.Message)
+			return
+		}
+		if apiErr := job.applyQuery(query, "regions", "format"); apiErr != nil {
+			writeAPIProxyError(w, apiErr.Status, apiErr.Code, apiErr.Message)
+			return
+		}
+		items, results, apiErr := s.fanOutCollect(r.Context(), job, query.Get("regions"))
+		if apiErr != nil {
+			writeAPIProxyError(w, apiErr.Status, apiErr.Code, apiErr.Message)
+			return
+		}
+		var incomplete []string
+		for _, result := range results {
+			if result.Code != "" || result.Truncated {
+				incomplete = append(incomplete, result.Region)
+			}
+		}
+		if len(incomplete) == len(results) && len(items) == 0 {
+			writeAPIProxyError(w, http.StatusBadGateway, results[0].Code, results[0].Message)
+			return
+		}
+		if len(incomplete) > 0 {
+			w.Header().Set("X-Incomplete-Regions", strings.Join(incomplete, ","))
+		}
+		rows = exportRows(items)
+	}
+
+	// 行中带有签名算法的地域字段（聚合查询的结果，或阿里云自带的 RegionId）时加上地域列
+	if signer, ok := s.signers[apiSignerName(config)]; ok {
+		regionParam := signer.RegionParam()
+		for _, row := range rows {
+			if _, ok := row[regionParam]; ok {
+				columns = append([]tableColumn{{Label: "地域", Field: regionParam, DefaultValue: "-"}}, columns...)
+				break
+			}
+		}
+	}
+
+	sheet := tableName
+	tables, _ := config["tables"].(map[string]interface{})
+	if entry, ok := tables[tableName].(map[string]interface{}); ok {
+		if title, _ := entry["title"].(string); title != "" {
+			sheet = title
+		}
+	}
+
+	filename := fmt.Sprintf("%s-%s.%s", tableName, time.Now().Format("20060102-150405"), format.Extension)
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("Cache-Control", "no-store")
+	if err := format.Write(w, sheet, columns, rows); err != nil {
+		s.logger.Printf("[Export] %s/%s %s: %v", siteName, tableName, formatName, err)
+	}
+}
+
+// exportRows 只保留对象类型的行
+func exportRows(items []interface{}) []map[string]interface{} {
+	rows := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		if row, ok := item.(map[string]interface{}); ok {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
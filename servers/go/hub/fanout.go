@@ -0,0 +1,478 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	fanOutMaxRegions       = 100      // 一次聚合查询最多的地域数
+	fanOutMaxResponseBytes = 32 << 20 // 每页响应的最大字节数
+)
+
+// FanOutConfig 跨地域聚合查询（/{site}/api/fanout/{table}）的限制
+// 每个地域依次读取所有分页，地域之间并发；单个地域失败或超时只影响该地域的结果
+type FanOutConfig struct {
+	Concurrency   int           // 同时查询的地域数，0 表示 8
+	RegionTimeout time.Duration // 每个地域（包括所有分页）的超时时间，0 表示 20 秒
+	MaxPages      int           // 每个地域最多读取的页数，0 表示 20，超过时该地域的结果标记为 Truncated
+}
+
+// Validate 检查配置是否可用
+func (c FanOutConfig) Validate() error {
+	if c.Concurrency < 0 || c.RegionTimeout < 0 || c.MaxPages < 0 {
+		return fmt.Errorf("concurrency, region_timeout and max_pages must not be negative")
+	}
+	return nil
+}
+
+// withDefaults 填充未配置的字段
+func (c FanOutConfig) withDefaults() FanOutConfig {
+	if c.Concurrency == 0 {
+		c.Concurrency = 8
+	}
+	if c.RegionTimeout == 0 {
+		c.RegionTimeout = 20 * time.Second
+	}
+	if c.MaxPages == 0 {
+		c.MaxPages = 20
+	}
+	return c
+}
+
+// fanOutTable 站点配置 tables.{table} 中聚合查询使用的字段
+type fanOutTable struct {
+	Product  string // api.{product}，接口所属的产品
+	Action   string // apiFunction，与接口名相同
+	DataPath string // dataPath，结果列表在响应中的路径，例如 Instances.Instance
+	RowKey   string // rowKey，行的唯一键，资产快照按它对比；没有配置时为空
+
+	QueryParams []string // queryParams，可以通过查询参数传给上游的过滤条件，例如 Status
+}
+
+// fanOutTableFor 读取站点配置中的表格定义
+func fanOutTableFor(config Config, table string) (fanOutTable, bool) {
+	tables, _ := config["tables"].(map[string]interface{})
+	entry, _ := tables[table].(map[string]interface{})
+	product, _ := entry["product"].(string)
+	action, _ := entry["apiFunction"].(string)
+	dataPath, _ := entry["dataPath"].(string)
+	if product == "" || action == "" || dataPath == "" {
+		return fanOutTable{}, false
+	}
+	rowKey, _ := entry["rowKey"].(string)
+	var queryParams []string
+	list, _ := entry["queryParams"].([]interface{})
+	for _, item := range list {
+		if name, ok := item.(string); ok && name != "" {
+			queryParams = append(queryParams, name)
+		}
+	}
+	return fanOutTable{Product: product, Action: action, DataPath: dataPath, RowKey: rowKey, QueryParams: queryParams}, true
+}
+
+// apiPagination 站点配置 api.pagination，未配置的字段使用阿里云的页码分页
+type apiPagination struct {
+	PageParam string // 页码参数，默认 PageNumber，页码从 1 开始
+	SizeParam string // 每页数量参数，默认 PageSize
+	PageSize  int    // 每页数量，默认 50
+	TotalPath string // 总数在响应中的路径，默认 TotalCount
+}
+
+// apiPaginationFor 读取站点配置中的分页方式
+func apiPaginationFor(config Config) apiPagination {
+	products, _ := config["api"].(map[string]interface{})
+	entry, _ := products["pagination"].(map[string]interface{})
+	pagination := apiPagination{PageParam: "PageNumber", SizeParam: "PageSize", PageSize: 50, TotalPath: "TotalCount"}
+	if value, _ := entry["pageParam"].(string); value != "" {
+		pagination.PageParam = value
+	}
+	if value, _ := entry["sizeParam"].(string); value != "" {
+		pagination.SizeParam = value
+	}
+	if value, _ := entry["pageSize"].(float64); value >= 1 {
+		pagination.PageSize = int(value)
+	}
+	if value, _ := entry["totalPath"].(string); value != "" {
+		pagination.TotalPath = value
+	}
+	return pagination
+}
+
+// apiRegionList 站点配置 api.regions：请求没有指定地域时，用于查询全部地域的接口
+type apiRegionList struct {
+	Product  string // 接口所属的产品
+	Action   string // 接口名，例如 DescribeRegions
+	Region   string // 调用该接口使用的地域
+	DataPath string // 地域列表在响应中的路径，例如 Regions.Region
+	Field    string // 地域 ID 的字段名，例如 RegionId
+}
+
+// apiRegionListFor 读取站点配置中的地域列表接口
+func apiRegionListFor(config Config) (apiRegionList, bool) {
+	products, _ := config["api"].(map[string]interface{})
+	entry, _ := products["regions"].(map[string]interface{})
+	var list apiRegionList
+	list.Product, _ = entry["product"].(string)
+	list.Action, _ = entry["action"].(string)
+	list.Region, _ = entry["region"].(string)
+	list.DataPath, _ = entry["dataPath"].(string)
+	list.Field, _ = entry["field"].(string)
+	if list.Product == "" || list.Action == "" || list.DataPath == "" || list.Field == "" {
+		return apiRegionList{}, false
+	}
+	return list, true
+}
+
+// fanOutRegionResult 一个地域的查询结果；失败时 Code/Message 为云服务 API 或代理的错误，
+// 失败前已读取的分页仍然保留在合并结果中
+type fanOutRegionResult struct {
+	Region    string `json:"Region"`
+	Count     int    `json:"Count"`
+	Pages     int    `json:"Pages"`
+	Truncated bool   `json:"Truncated,omitempty"`
+	Code      string `json:"Code,omitempty"`
+	Message   string `json:"Message,omitempty"`
+
+	items []interface{}
+}
+
+// fanOutJob 一次聚合查询的公共参数
+type fanOutJob struct {
+	config      Config
+	siteName    string
+	credential  Credential
+	tableName   string
+	regionParam string
+	table       fanOutTable
+	pagination  apiPagination
+	params      map[string]string
+}
+
+// handleAPIFanOut 处理 /{site}/api/fanout/{table}：
+// 按表格的 apiFunction 并发查询多个地域（regions 参数，逗号分隔；未指定时查询 api.regions 返回的全部地域），
+// 每个地域读取所有分页，合并到表格 dataPath 对应的列表中，每一行带上所属地域（签名算法的 RegionParam 字段）；
+// 表格 queryParams 中列出的查询参数原样传给每个地域的请求，其他参数返回 400。单个地域失败时在 RegionResults 中报告，整体仍返回 200
+func (s *Server) handleAPIFanOut(w http.ResponseWriter, r *http.Request, state *siteState, siteName, origin, tableName string) {
+	if s.credentials == nil {
+		http.NotFound(w, r)
+		return
+	}
+	credential, ok := s.credentials.Credential(siteName)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeAPIProxyError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "Only GET is allowed")
+		return
+	}
+	if _, ok := s.authenticateAPI(w, r, siteName, origin); !ok {
+		return
+	}
+
+	job, apiErr := s.newFanOutJob(state.siteConfigs[siteName], siteName, credential, tableName)
+	if apiErr != nil {
+		writeAPIProxyError(w, apiErr.Status, apiErr.Code, apiErr.Message)
+		return
+	}
+	if apiErr := job.applyQuery(r.URL.Query(), "regions"); apiErr != nil {
+		writeAPIProxyError(w, apiErr.Status, apiErr.Code, apiErr.Message)
+		return
+	}
+
+	items, results, apiErr := s.fanOutCollect(r.Context(), job, r.URL.Query().Get("regions"))
+	if apiErr != nil {
+		writeAPIProxyError(w, apiErr.Status, apiErr.Code, apiErr.Message)
+		return
+	}
+
+	response := map[string]interface{}{"RegionResults": results}
+	setPath(response, job.table.DataPath, items)
+	setPath(response, job.pagination.TotalPath, len(items))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(response)
+}
+
+// newFanOutJob 按站点配置中的表格定义和签名算法准备聚合查询，params 为空
+func (s *Server) newFanOutJob(config Config, siteName string, credential Credential, tableName string) (fanOutJob, *apiError) {
+	table, ok := fanOutTableFor(config, tableName)
+	if !ok {
+		return fanOutJob{}, &apiError{http.StatusNotFound, "UnknownTable", "Table is not configured for fan-out: " + tableName}
+	}
+	signerName := apiSignerName(config)
+	signer, ok := s.signers[signerName]
+	if !ok {
+		s.logger.Printf("[APIProxy] %s: unknown signer %q", siteName, signerName)
+		return fanOutJob{}, &apiError{http.StatusInternalServerError, "UnknownSigner", "Signer is not available: " + signerName}
+	}
+	return fanOutJob{
+		config:      config,
+		siteName:    siteName,
+		credential:  credential,
+		tableName:   tableName,
+		regionParam: signer.RegionParam(),
+		table:       table,
+		pagination:  apiPaginationFor(config),
+		params:      make(map[string]string),
+	}, nil
+}
+
+// applyQuery 把查询参数中表格 queryParams 允许的过滤条件加入每个地域的请求，control 为接口自身使用的参数（不传给上游）；
+// 以服务端凭证签名的请求不接受任意参数，其他参数（包括地域和分页参数）返回 InvalidParameter
+func (job *fanOutJob) applyQuery(query url.Values, control ...string) *apiError {
+	for key, values := range query {
+		if len(values) == 0 || slices.Contains(control, key) {
+			continue
+		}
+		if !slices.Contains(job.table.QueryParams, key) || key == job.regionParam ||
+			key == job.pagination.PageParam || key == job.pagination.SizeParam {
+			return &apiError{http.StatusBadRequest, "InvalidParameter", "Parameter is not allowed for table " + job.tableName + ": " + key}
+		}
+		job.params[key] = values[0]
+	}
+	return nil
+}
+
+// fanOutCollect 并发查询所有地域（requested 为逗号分隔的地域，为空时查询全部地域），
+// 按地域顺序合并结果；单个地域的失败只记录在对应的 fanOutRegionResult 中
+func (s *Server) fanOutCollect(ctx context.Context, job fanOutJob, requested string) ([]interface{}, []fanOutRegionResult, *apiError) {
+	regions, apiErr := s.fanOutRegionList(ctx, job, requested)
+	if apiErr != nil {
+		return nil, nil, apiErr
+	}
+
+	results := make([]fanOutRegionResult, len(regions))
+	slots := make(chan struct{}, s.fanOut.Concurrency)
+	var wg sync.WaitGroup
+	for i, region := range regions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			results[i] = s.fanOutRegion(ctx, job, region)
+		}()
+	}
+	wg.Wait()
+
+	// 按地域顺序合并，结果与地域列表的顺序一致
+	var items []interface{}
+	failed := 0
+	for _, result := range results {
+		items = append(items, result.items...)
+		if result.Code != "" {
+			failed++
+		}
+	}
+	if items == nil {
+		items = []interface{}{}
+	}
+	if failed > 0 && ctx.Err() == nil {
+		s.logger.Printf("[APIProxy] %s fan-out %s: %d/%d regions failed", job.siteName, job.tableName, failed, len(regions))
+	}
+	return items, results, nil
+}
+
+// fanOutRegionList 解析 regions 参数（逗号分隔），为空时通过 api.regions 配置的接口查询全部地域
+func (s *Server) fanOutRegionList(ctx context.Context, job fanOutJob, requested string) ([]string, *apiError) {
+	var regions []string
+	seen := make(map[string]bool)
+	add := func(region string) bool {
+		if !apiProxyRegionPattern.MatchString(region) {
+			return false
+		}
+		if !seen[region] {
+			seen[region] = true
+			regions = append(regions, region)
+		}
+		return true
+	}
+
+	if requested != "" {
+		for _, region := range strings.Split(requested, ",") {
+			if !add(strings.TrimSpace(region)) {
+				return nil, &apiError{http.StatusBadRequest, "InvalidRegion", "Invalid region in regions: " + region}
+			}
+		}
+	} else {
+		list, ok := apiRegionListFor(job.config)
+		if !ok {
+			return nil, &apiError{http.StatusBadRequest, "MissingRegion", "regions is required"}
+		}
+		ctx, cancel := context.WithTimeout(ctx, s.fanOut.RegionTimeout)
+		defer cancel()
+		doc, apiErr := s.fanOutCall(ctx, job, list.Product, list.Action, map[string]string{job.regionParam: list.Region})
+		if apiErr != nil {
+			return nil, apiErr
+		}
+		entries, _ := lookupPath(doc, list.DataPath).([]interface{})
+		for _, entry := range entries {
+			if fields, ok := entry.(map[string]interface{}); ok {
+				region, _ := fields[list.Field].(string)
+				add(region)
+			}
+		}
+	}
+
+	if len(regions) == 0 {
+		return nil, &apiError{http.StatusBadRequest, "MissingRegion", "No regions to query"}
+	}
+	if len(regions) > fanOutMaxRegions {
+		return nil, &apiError{http.StatusBadRequest, "TooManyRegions", fmt.Sprintf("At most %d regions can be queried at once", fanOutMaxRegions)}
+	}
+	return regions, nil
+}
+
+// fanOutRegion 查询一个地域的所有分页
+func (s *Server) fanOutRegion(ctx context.Context, job fanOutJob, region string) fanOutRegionResult {
+	ctx, cancel := context.WithTimeout(ctx, s.fanOut.RegionTimeout)
+	defer cancel()
+
+	result := fanOutRegionResult{Region: region}
+	size := job.pagination.PageSize
+	for page := 1; ; page++ {
+		if page > s.fanOut.MaxPages {
+			result.Truncated = true
+			break
+		}
+
+		params := make(map[string]string, len(job.params)+3)
+		for key, value := range job.params {
+			params[key] = value
+		}
+		params[job.regionParam] = region
+		params[job.pagination.PageParam] = strconv.Itoa(page)
+		params[job.pagination.SizeParam] = strconv.Itoa(size)
+
+		doc, apiErr := s.fanOutCall(ctx, job, job.table.Product, job.table.Action, params)
+		if apiErr != nil {
+			result.Code, result.Message = apiErr.Code, apiErr.Message
+			break
+		}
+		result.Pages = page
+
+		items, _ := lookupPath(doc, job.table.DataPath).([]interface{})
+		for _, item := range items {
+			if fields, ok := item.(map[string]interface{}); ok {
+				fields[job.regionParam] = region
+			}
+		}
+		result.items = append(result.items, items...)
+
+		// 有总数时读到总数为止，没有时读到不满一页为止
+		total, hasTotal := jsonInt(lookupPath(doc, job.pagination.TotalPath))
+		if len(items) == 0 || (hasTotal && len(result.items) >= total) || (!hasTotal && len(items) < size) {
+			break
+		}
+	}
+	result.Count = len(result.items)
+	return result
+}
+
+// fanOutCall 签名并发送一次 API 请求，解析 JSON 响应；网络错误、超时和云服务 API 返回的错误都转换为 apiError
+func (s *Server) fanOutCall(ctx context.Context, job fanOutJob, product, action string, params map[string]string) (map[string]interface{}, *apiError) {
+	req, apiErr := s.newAPIRequest(ctx, job.config, job.siteName, job.credential, product, action, params, nil)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	resp, err := s.apiClient.Do(req)
+	if err != nil {
+		if apiErr := fanOutContextError(ctx); apiErr != nil {
+			return nil, apiErr
+		}
+		s.logger.Printf("[APIProxy] %s %s/%s %s: %v", job.siteName, product, action, params[job.regionParam], upstreamError(err))
+		return nil, &apiError{http.StatusBadGateway, "ProxyError", "Upstream request failed"}
+	}
+	defer resp.Body.Close()
+
+	var doc map[string]interface{}
+	decoder := json.NewDecoder(io.LimitReader(resp.Body, fanOutMaxResponseBytes))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		if apiErr := fanOutContextError(ctx); apiErr != nil {
+			return nil, apiErr
+		}
+		return nil, &apiError{http.StatusBadGateway, "InvalidResponse", fmt.Sprintf("Upstream returned a non-JSON response (HTTP %d)", resp.StatusCode)}
+	}
+	if code, message := apiResponseError(doc); code != "" || resp.StatusCode != http.StatusOK {
+		if code == "" {
+			code, message = "UpstreamError", fmt.Sprintf("Upstream returned HTTP %d", resp.StatusCode)
+		}
+		return nil, &apiError{resp.StatusCode, code, message}
+	}
+	return doc, nil
+}
+
+// fanOutContextError 请求因超时或客户端断开而失败时返回对应的错误
+func fanOutContextError(ctx context.Context) *apiError {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return &apiError{http.StatusGatewayTimeout, "Timeout", "Region did not respond in time"}
+	case ctx.Err() != nil:
+		return &apiError{http.StatusServiceUnavailable, "Canceled", "Request was canceled"}
+	}
+	return nil
+}
+
+// apiResponseError 云服务 API 响应中的错误：阿里云在顶层的 Code/Message，腾讯云在 Response.Error 中
+func apiResponseError(doc map[string]interface{}) (string, string) {
+	fields := doc
+	if nested, ok := lookupPath(doc, "Response.Error").(map[string]interface{}); ok {
+		fields = nested
+	}
+	code, _ := fields["Code"].(string)
+	message, _ := fields["Message"].(string)
+	return code, message
+}
+
+// lookupPath 按点分隔的路径读取 JSON 文档中的值，路径不存在时返回 nil
+func lookupPath(doc interface{}, path string) interface{} {
+	for _, key := range strings.Split(path, ".") {
+		fields, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		doc = fields[key]
+	}
+	return doc
+}
+
+// setPath 按点分隔的路径写入 JSON 文档，缺少的中间对象会被创建
+func setPath(doc map[string]interface{}, path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := doc[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			doc[key] = next
+		}
+		doc = next
+	}
+	doc[keys[len(keys)-1]] = value
+}
+
+// jsonInt 将 JSON 数字（json.Number）转换为整数
+func jsonInt(value interface{}) (int, bool) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	n, err := number.Int64()
+	return int(n), err == nil
+}
@@ -0,0 +1,65 @@
+package hub
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestAPIFanOutQueryParams(t *testing.T) {
+	var received url.Values
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.URL.Query()
+		io.WriteString(w, `{"Instances": {"Instance": [{"InstanceId": "i-1"}]}, "TotalCount": 1}`)
+	}))
+	defer upstream.Close()
+	srv := newAPITestServer(t, upstream.URL, Options{APIUsers: []APIUser{{Name: "alice", TokenSHA256: HashAPIToken("alice-token")}}})
+
+	do := func(method, target, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "http://example.com"+target, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		token  string
+		want   int
+	}{
+		{"fan-out without token", "/aliyun/api/fanout/ecs_instances?regions=cn-hangzhou", "", http.StatusUnauthorized},
+		{"export without token", "/aliyun/api/export/ecs_instances?regions=cn-hangzhou", "", http.StatusUnauthorized},
+		{"allowed filter", "/aliyun/api/fanout/ecs_instances?regions=cn-hangzhou&Status=Running", "alice-token", http.StatusOK},
+		{"export with allowed filter", "/aliyun/api/export/ecs_instances?format=ndjson&regions=cn-hangzhou&Status=Running", "alice-token", http.StatusOK},
+		{"parameter not in queryParams", "/aliyun/api/fanout/ecs_instances?regions=cn-hangzhou&AccessKeyId=x", "alice-token", http.StatusBadRequest},
+		{"pagination parameter", "/aliyun/api/export/ecs_instances?regions=cn-hangzhou&PageSize=1000", "alice-token", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received = nil
+			rec := do(http.MethodGet, tt.target, tt.token, "")
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.want == http.StatusOK && received.Get("Status") != "Running" {
+				t.Fatalf("upstream query = %v, want Status=Running", received)
+			}
+			if tt.want != http.StatusOK && received != nil {
+				t.Fatalf("upstream called for rejected request: %v", received)
+			}
+		})
+	}
+
+	// POST 导出只转换请求体中的行，不使用服务端凭证，不需要登录
+	rec := do(http.MethodPost, "/aliyun/api/export/ecs_instances?format=ndjson", "", `[{"InstanceId": "i-2"}]`)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"i-2"`) {
+		t.Fatalf("POST export: status = %d, body = %s", rec.Code, rec.Body)
+	}
+}
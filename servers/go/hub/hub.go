@@ -0,0 +1,626 @@
+// Package hub 实现 Jinja Hub 平台的 HTTP 服务
+//
+// Server 实现了 http.Handler，可以单独运行，也可以挂载到其他 Go 服务的子路径下：
+//
+//	srv, err := hub.New(hub.Options{
+//		SitesFS: os.DirFS("/srv/jinja-hub/sites"),
+//		Prefix:  "/hub",
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	mux.Handle("/hub/", srv)
+package hub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/flosch/pongo2/v6"
+)
+
+// Options Server 的配置项
+type Options struct {
+	// SitesFS 站点根目录（包含 sites.json），必填
+	SitesFS fs.FS
+
+	// Prefix 挂载路径前缀，例如 "/hub"；为空表示挂载在根路径
+	// 请求路径必须以该前缀开头，模板中的 base_path 和 root_path 会自动带上前缀
+	Prefix string
+
+	// Limiter 速率限制器，为 nil 时使用默认配置的内存限制器
+	Limiter Limiter
+
+	// EgressRate 所有响应合计的出口带宽上限（字节/秒），为 0 时不限制
+	EgressRate int64
+
+	// MaxBodyBytes 请求体大小上限，为 0 时使用 10MB
+	MaxBodyBytes int64
+
+	// CDNCacheDir CDN 文件的磁盘缓存目录，为空时禁用 /cdn/ 代理
+	CDNCacheDir string
+
+	// CDNFS 只读的预置 CDN 缓存（例如内嵌到二进制中的文件），优先级低于 CDNCacheDir
+	CDNFS fs.FS
+
+	// AdminToken 管理接口令牌，为空时管理接口仅允许本机直接访问（不带转发头）
+	AdminToken string
+
+	// TrustedProxies 受信任的反向代理，来自这些地址的请求会采信
+	// Forwarded、X-Forwarded-For/Proto/Host 和 X-Real-IP 请求头
+	TrustedProxies TrustedProxies
+
+	// Concurrency 并发限制和过载保护，MaxInFlight 和 MaxInFlightPerSite 都为 0 时不启用
+	Concurrency ConcurrencyConfig
+
+	// Credentials 服务端保存的云服务凭证，配置了凭证的站点启用 /{site}/api/proxy/{product}/{action}
+	// 签名代理，浏览器无需持有 AccessKey；为 nil 时不启用
+	Credentials CredentialStore
+
+	// APIUsers 可以使用服务端凭证接口的用户，调用者通过 Authorization: Bearer 访问令牌或 /{site}/api/session 登录后的
+	// 会话 Cookie 识别；为空时这些接口拒绝所有请求
+	APIUsers []APIUser
+
+	// SessionTTL 浏览器会话的有效期，0 表示 12 小时
+	SessionTTL time.Duration
+
+	// APIClient 签名代理访问云服务 API 使用的 HTTP 客户端，为 nil 时使用 30 秒超时的默认客户端
+	APIClient *http.Client
+
+	// Signers 额外的签名算法，站点在 config.json 的 api.signer 中按名称选择；
+	// 内置 aliyun-rpc、aws-sigv4 和 tc3，同名时替换内置实现
+	Signers map[string]Signer
+
+	// FanOut 跨地域聚合查询 /{site}/api/fanout/{table} 的并发数、每个地域的超时时间和最多读取的页数，
+	// 零值字段使用默认值；该接口和签名代理一样只对配置了凭证的站点开放
+	FanOut FanOutConfig
+
+	// Policy 资源管理操作 /{site}/api/manage/{resource}/{action} 和签名代理中非只读接口的放行、拒绝和确认规则，
+	// 零值拒绝所有这些操作；该接口和签名代理一样只对配置了凭证的站点开放
+	Policy Policy
+
+	// AuditLog 资源管理操作和签名代理中非只读接口的审计日志，可通过 /_admin/audit 查询；为 nil 时写入 Logger
+	AuditLog AuditLog
+
+	// Inventory 资产快照的目录、间隔和参与的表格；定期快照由 RunInventory 执行，
+	// 快照和对比通过 /{site}/api/inventory/{table} 查询，Dir 为空时不启用
+	Inventory InventoryConfig
+
+	// StatusStream 资源状态推送 /{site}/api/status/{table} 的轮询间隔、稳定时间、连接时长和关注的资源数，
+	// 零值字段使用默认值；该接口和签名代理一样只对配置了凭证的站点开放
+	StatusStream StatusStreamConfig
+
+	// Ban 自动封禁频繁触发 404/403/400 和路径遍历的客户端，Threshold 为 0 时不启用
+	Ban BanConfig
+
+	// Logger 日志输出，为 nil 时使用 log.Default()
+	Logger *log.Logger
+
+	// Filters 额外注册的 pongo2 过滤器
+	// pongo2 的过滤器是进程级全局的，同名过滤器会被替换
+	Filters map[string]pongo2.FilterFunction
+}
+
+// Server Jinja Hub 服务，实现 http.Handler
+type Server struct {
+	sitesFS      fs.FS
+	prefix       string
+	limiter      Limiter
+	shaper       *egressShaper
+	maxBodyBytes int64
+	cdnCacheDir  string
+	cdnFS        fs.FS
+	adminToken   string
+	logger       *log.Logger
+
+	trustedProxies TrustedProxies
+	bans           *banList            // 未启用自动封禁时为 nil
+	concurrency    *concurrencyLimiter // 未启用并发限制时为 nil
+	credentials    CredentialStore     // 未启用签名代理时为 nil
+	apiUsers       *apiUsers
+	apiSessions    *apiSessions
+	apiClient      *http.Client
+	signers        map[string]Signer
+	fanOut         FanOutConfig
+	policy         Policy
+	confirmTokens  *confirmTokens
+	auditLog       AuditLog // 未配置时为 nil
+	inventory      InventoryConfig
+	inventoryMu    sync.Mutex // 同一时间只有一次资产快照在进行
+	statusStream   StatusStreamConfig
+	statusMu       sync.Mutex // 保护 statusPollers 和其中的订阅者
+	statusPollers  map[statusPollerKey]*statusPoller
+
+	// state 当前生效的站点状态快照，reloadMu 保证同一时间只有一个重载在进行
+	state    atomic.Pointer[siteState]
+	reloadMu sync.Mutex
+}
+
+var filtersMu sync.Mutex
+
+func init() {
+	// 注册自定义 filter: json
+	pongo2.RegisterFilter("json", func(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+		jsonBytes, err := json.Marshal(in.Interface())
+		if err != nil {
+			return nil, &pongo2.Error{
+				Sender:    "filter:json",
+				OrigError: err,
+			}
+		}
+		return pongo2.AsValue(string(jsonBytes)), nil
+	})
+
+	// 启用沙盒模式
+	pongo2.SetAutoescape(true)
+}
+
+// New 创建 Server 并加载所有站点
+func New(opts Options) (*Server, error) {
+	if opts.SitesFS == nil {
+		return nil, errors.New("hub: SitesFS is required")
+	}
+
+	s := &Server{
+		sitesFS:      opts.SitesFS,
+		prefix:       strings.TrimSuffix(opts.Prefix, "/"),
+		limiter:      opts.Limiter,
+		shaper:       newEgressShaper(opts.EgressRate),
+		maxBodyBytes: opts.MaxBodyBytes,
+		cdnCacheDir:  opts.CDNCacheDir,
+		cdnFS:        opts.CDNFS,
+		adminToken:   opts.AdminToken,
+		logger:       opts.Logger,
+
+		trustedProxies: opts.TrustedProxies,
+		credentials:    opts.Credentials,
+		apiClient:      opts.APIClient,
+		auditLog:       opts.AuditLog,
+	}
+	if s.prefix != "" && !strings.HasPrefix(s.prefix, "/") {
+		return nil, errors.New("hub: Prefix must start with /")
+	}
+	if s.logger == nil {
+		s.logger = log.Default()
+	}
+	if s.limiter == nil {
+		s.limiter = NewRateLimiter(DefaultRateLimitConfig, s.logger)
+	}
+	apiUsers, err := newAPIUsers(opts.APIUsers)
+	if err != nil {
+		return nil, fmt.Errorf("hub: APIUsers%w", err)
+	}
+	s.apiUsers = apiUsers
+	if opts.SessionTTL < 0 {
+		return nil, errors.New("hub: SessionTTL must not be negative")
+	}
+	if opts.SessionTTL == 0 {
+		opts.SessionTTL = 12 * time.Hour
+	}
+	s.apiSessions = newAPISessions(opts.SessionTTL)
+	if s.apiClient == nil {
+		s.apiClient = &http.Client{Timeout: apiProxyTimeout}
+	}
+	s.signers = builtinSigners()
+	for name, signer := range opts.Signers {
+		s.signers[name] = signer
+	}
+	if err := opts.FanOut.Validate(); err != nil {
+		return nil, fmt.Errorf("hub: FanOut: %w", err)
+	}
+	s.fanOut = opts.FanOut.withDefaults()
+	if err := opts.Policy.Validate(); err != nil {
+		return nil, fmt.Errorf("hub: Policy: %w", err)
+	}
+	s.policy = opts.Policy.withDefaults()
+	s.confirmTokens = newConfirmTokens(s.policy.ConfirmTTL)
+	if err := opts.Inventory.Validate(); err != nil {
+		return nil, fmt.Errorf("hub: Inventory: %w", err)
+	}
+	s.inventory = opts.Inventory
+	if err := opts.StatusStream.Validate(); err != nil {
+		return nil, fmt.Errorf("hub: StatusStream: %w", err)
+	}
+	s.statusStream = opts.StatusStream.withDefaults()
+	s.statusPollers = make(map[statusPollerKey]*statusPoller)
+	if s.maxBodyBytes <= 0 {
+		s.maxBodyBytes = 10 << 20 // 10MB
+	}
+	if opts.Concurrency.MaxInFlight > 0 || opts.Concurrency.MaxInFlightPerSite > 0 {
+		if err := opts.Concurrency.Validate(); err != nil {
+			return nil, fmt.Errorf("hub: Concurrency: %w", err)
+		}
+		s.concurrency = newConcurrencyLimiter(opts.Concurrency, s.logger)
+	}
+	if opts.Ban.Threshold > 0 {
+		if err := opts.Ban.Validate(); err != nil {
+			return nil, fmt.Errorf("hub: Ban: %w", err)
+		}
+		s.bans = newBanList(opts.Ban, s.logger)
+	}
+
+	if err := registerFilters(opts.Filters); err != nil {
+		return nil, err
+	}
+
+	// 加载站点配置和模板
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// registerFilters 注册额外的 pongo2 过滤器
+func registerFilters(filters map[string]pongo2.FilterFunction) error {
+	filtersMu.Lock()
+	defer filtersMu.Unlock()
+
+	for name, fn := range filters {
+		var err error
+		if pongo2.FilterExists(name) {
+			err = pongo2.ReplaceFilter(name, fn)
+		} else {
+			err = pongo2.RegisterFilter(name, fn)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Platform 返回当前加载的平台信息
+func (s *Server) Platform() PlatformInfo {
+	return s.getState().sitesConfig.Platform
+}
+
+// Sites 返回当前加载的站点列表
+func (s *Server) Sites() map[string]SiteInfo {
+	sites := make(map[string]SiteInfo)
+	for name, info := range s.getState().sitesConfig.Sites {
+		sites[name] = info
+	}
+	return sites
+}
+
+// ServeHTTP 处理所有路由
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// 去掉挂载前缀
+	urlPath := r.URL.Path
+	if s.prefix != "" {
+		if urlPath != s.prefix && !strings.HasPrefix(urlPath, s.prefix+"/") {
+			http.NotFound(w, r)
+			return
+		}
+		urlPath = strings.TrimPrefix(urlPath, s.prefix)
+		if urlPath == "" {
+			urlPath = "/"
+		}
+	}
+
+	// 限制请求体大小
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxBodyBytes)
+
+	// 整个请求使用同一个状态快照
+	state := s.getState()
+
+	// 解析真实的客户端 IP、协议和 Host（经过可信代理时采信转发头）
+	r, info := s.withRequestInfo(r)
+	host := hostname(info.host)
+
+	site, route := classifyRequest(state, host, urlPath)
+
+	// 被封禁的客户端直接拒绝；管理员仍可访问管理接口以解除封禁
+	if s.bans != nil {
+		if ban, banned := s.bans.banned(info.clientIP); banned && !(route == RouteAdmin && s.authorizeAdmin(r)) {
+			w.Header().Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(time.Until(ban.Until)))))
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+	}
+
+	// 速率限制检查（请求数和流量）
+	limitKey := RateLimitKey{IP: info.clientIP, Site: site, Route: route}
+	result := s.limiter.Allow(limitKey)
+	setRateLimitHeaders(w.Header(), result)
+	if !result.Allowed {
+		http.Error(w, result.Reason, http.StatusTooManyRequests)
+		return
+	}
+
+	// 并发限制：超过并发数时排队，排不上时返回 503；持续很久的响应使用单独的流式通道
+	if s.concurrency != nil {
+		release, retryAfter, ok := s.concurrency.acquire(r.Context(), site, route, s.isLongResponse(route, urlPath, result.ThrottleRate))
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		defer release()
+	}
+
+	// 响应边写边压缩，按实际写出的字节数记录流量；流量超出预算（整形模式）或有总带宽上限时限速写出
+	tw := &trafficWriter{ResponseWriter: s.shaper.wrap(w, r, info.clientIP, result.ThrottleRate)}
+	cw := newCompressWriter(tw, r)
+	defer func() {
+		cw.Close()
+		s.limiter.RecordTraffic(limitKey, tw.written)
+		if s.bans != nil && !s.banExempt(info.clientIP) {
+			s.bans.observe(info.clientIP, tw.status, isTraversalAttempt(urlPath), r.Method+" "+r.URL.RequestURI())
+		}
+	}()
+
+	s.route(cw, r, state, host, info.scheme+"://"+info.host, urlPath)
+}
+
+// classifyRequest 确定请求所属的站点和路由类别，用于选择速率限制规则
+func classifyRequest(state *siteState, host, urlPath string) (site, route string) {
+	if strings.HasPrefix(urlPath, "/cdn/") {
+		return "", RouteCDN
+	}
+	if strings.HasPrefix(urlPath, "/_admin/") {
+		return "", RouteAdmin
+	}
+	if urlPath == "/_health" {
+		return "", RouteHealth
+	}
+
+	// 域名模式下路径不含站点名
+	rest := strings.TrimPrefix(urlPath, "/")
+	if siteName, exists := state.domainToSite[host]; exists {
+		site = siteName
+	} else {
+		siteName, remainder, _ := strings.Cut(rest, "/")
+		if _, exists := state.sitesConfig.Sites[siteName]; !exists {
+			return "", RoutePage
+		}
+		site, rest = siteName, remainder
+	}
+
+	switch {
+	case strings.HasPrefix(rest, "static/"):
+		return site, RouteStatic
+	case rest == "api" || strings.HasPrefix(rest, "api/"):
+		return site, RouteAPI
+	default:
+		return site, RoutePage
+	}
+}
+
+// isLongResponse 响应是否会持续很久：状态推送和被限速写出的响应；
+// 配置了总带宽上限（egress_rate）时，静态文件、CDN 文件和导出的写出速度取决于共享的带宽，同样可能持续很久
+func (s *Server) isLongResponse(route, urlPath string, throttleRate int64) bool {
+	switch {
+	case throttleRate > 0 || (route == RouteAPI && isStatusStreamPath(urlPath)):
+		return true
+	case s.shaper.global == nil:
+		return false
+	}
+	return route == RouteStatic || route == RouteCDN || (route == RouteAPI && isAPITablePath(urlPath, "export"))
+}
+
+// isStatusStreamPath 是否为状态推送路径（/{site}/api/status/{table}，域名模式下为 /api/status/{table}）
+func isStatusStreamPath(urlPath string) bool {
+	return isAPITablePath(urlPath, "status")
+}
+
+// isAPITablePath 是否为 /{site}/api/{kind}/{table}（域名模式下为 /api/{kind}/{table}）形式的路径
+func isAPITablePath(urlPath, kind string) bool {
+	parts := strings.Split(strings.Trim(urlPath, "/"), "/")
+	n := len(parts)
+	return n >= 3 && n <= 4 && parts[n-3] == "api" && parts[n-2] == kind
+}
+
+// route 按路径分发请求，urlPath 已去掉挂载前缀
+// host 为不带端口的域名，用于域名映射；origin 为 协议://Host，用于生成绝对 URL
+func (s *Server) route(w http.ResponseWriter, r *http.Request, state *siteState, host, origin, urlPath string) {
+	// CDN 代理路由
+	if strings.HasPrefix(urlPath, "/cdn/") {
+		s.handleCDNProxy(w, r, strings.TrimPrefix(urlPath, "/cdn/"))
+		return
+	}
+
+	// 管理接口路由
+	if strings.HasPrefix(urlPath, "/_admin/") {
+		s.handleAdmin(w, r, strings.TrimPrefix(urlPath, "/_admin/"))
+		return
+	}
+
+	// 健康检查
+	if urlPath == "/_health" {
+		s.handleHealth(w, r, state)
+		return
+	}
+
+	// 检查域名映射
+	if siteName, exists := state.domainToSite[host]; exists {
+		// 域名直接映射到站点，处理站点路由
+		s.handleDomainSiteRoute(w, r, state, siteName, origin, urlPath)
+		return
+	}
+
+	// 根路径: 显示首页（所有站点列表）
+	if urlPath == "/" {
+		s.renderHomePage(w, r, state, origin)
+		return
+	}
+
+	// 站点路由处理
+	parts := strings.Split(strings.TrimPrefix(urlPath, "/"), "/")
+
+	if len(parts) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	siteName := parts[0]
+
+	// 检查站点是否存在
+	siteInfo, exists := state.sitesConfig.Sites[siteName]
+	if !exists {
+		http.NotFound(w, r)
+		return
+	}
+
+	// 检查站点是否启用
+	if !siteInfo.Enabled {
+		http.Error(w, "Site not enabled", http.StatusNotFound)
+		return
+	}
+
+	basePath := s.prefix + "/" + siteName
+
+	// 静态文件路由
+	if len(parts) >= 2 && parts[1] == "static" {
+		staticPath := path.Join(siteName, strings.Join(parts[1:], "/"))
+		s.serveStaticFile(w, r, staticPath)
+		return
+	}
+
+	// API 路由
+	if len(parts) >= 3 && parts[1] == "api" && parts[2] == "config" {
+		s.handleSiteAPIConfig(w, r, state, siteName)
+		return
+	}
+	if len(parts) == 3 && parts[1] == "api" && parts[2] == "session" {
+		s.handleAPISession(w, r, siteName, origin)
+		return
+	}
+	if len(parts) == 5 && parts[1] == "api" && parts[2] == "proxy" {
+		s.handleAPIProxy(w, r, state, siteName, origin, parts[3], parts[4])
+		return
+	}
+	if len(parts) == 4 && parts[1] == "api" && parts[2] == "fanout" {
+		s.handleAPIFanOut(w, r, state, siteName, origin, parts[3])
+		return
+	}
+	if len(parts) == 5 && parts[1] == "api" && parts[2] == "manage" {
+		s.handleAPIManage(w, r, state, siteName, origin, parts[3], parts[4])
+		return
+	}
+	if len(parts) == 4 && parts[1] == "api" && parts[2] == "export" {
+		s.handleAPIExport(w, r, state, siteName, origin, parts[3])
+		return
+	}
+	if (len(parts) == 4 || len(parts) == 5) && parts[1] == "api" && parts[2] == "inventory" {
+		s.handleAPIInventory(w, r, state, siteName, origin, parts[3], strings.Join(parts[4:], ""))
+		return
+	}
+	if len(parts) == 4 && parts[1] == "api" && parts[2] == "status" {
+		s.handleAPIStatus(w, r, state, siteName, origin, parts[3])
+		return
+	}
+
+	// 页面路由
+	if len(parts) == 1 || (len(parts) == 2 && (parts[1] == "" || parts[1] == "index.html")) {
+		// 站点首页 - 路径模式，base_path 为 /siteName
+		s.renderSitePageWithBasePath(w, r, state, siteName, "login", basePath, origin)
+		return
+	}
+
+	if len(parts) == 2 && strings.HasSuffix(parts[1], ".html") {
+		// 站点页面 - 路径模式，base_path 为 /siteName
+		pageName := strings.TrimSuffix(parts[1], ".html")
+		s.renderSitePageWithBasePath(w, r, state, siteName, pageName, basePath, origin)
+		return
+	}
+
+	http.NotFound(w, r)
+}
+
+// handleDomainSiteRoute 处理域名直接访问站点的路由
+func (s *Server) handleDomainSiteRoute(w http.ResponseWriter, r *http.Request, state *siteState, siteName, origin, urlPath string) {
+	// 检查站点是否存在和启用
+	siteInfo, exists := state.sitesConfig.Sites[siteName]
+	if !exists || !siteInfo.Enabled {
+		http.Error(w, "Site not found", http.StatusNotFound)
+		return
+	}
+
+	basePath := s.prefix + "/"
+
+	// 静态文件路由
+	if strings.HasPrefix(urlPath, "/static/") {
+		staticPath := path.Join(siteName, strings.TrimPrefix(urlPath, "/"))
+		s.serveStaticFile(w, r, staticPath)
+		return
+	}
+
+	// API 路由
+	if strings.HasPrefix(urlPath, "/api/config") {
+		s.handleSiteAPIConfig(w, r, state, siteName)
+		return
+	}
+	if urlPath == "/api/session" {
+		s.handleAPISession(w, r, siteName, origin)
+		return
+	}
+	if rest, ok := strings.CutPrefix(urlPath, "/api/proxy/"); ok {
+		if product, action, ok := strings.Cut(rest, "/"); ok && !strings.Contains(action, "/") {
+			s.handleAPIProxy(w, r, state, siteName, origin, product, action)
+			return
+		}
+	}
+	if table, ok := strings.CutPrefix(urlPath, "/api/fanout/"); ok && !strings.Contains(table, "/") {
+		s.handleAPIFanOut(w, r, state, siteName, origin, table)
+		return
+	}
+	if rest, ok := strings.CutPrefix(urlPath, "/api/manage/"); ok {
+		if resource, action, ok := strings.Cut(rest, "/"); ok && !strings.Contains(action, "/") {
+			s.handleAPIManage(w, r, state, siteName, origin, resource, action)
+			return
+		}
+	}
+	if table, ok := strings.CutPrefix(urlPath, "/api/export/"); ok && !strings.Contains(table, "/") {
+		s.handleAPIExport(w, r, state, siteName, origin, table)
+		return
+	}
+	if rest, ok := strings.CutPrefix(urlPath, "/api/inventory/"); ok {
+		if table, sub, _ := strings.Cut(rest, "/"); !strings.Contains(sub, "/") {
+			s.handleAPIInventory(w, r, state, siteName, origin, table, sub)
+			return
+		}
+	}
+	if table, ok := strings.CutPrefix(urlPath, "/api/status/"); ok && !strings.Contains(table, "/") {
+		s.handleAPIStatus(w, r, state, siteName, origin, table)
+		return
+	}
+
+	// 页面路由
+	if urlPath == "/" || urlPath == "" || urlPath == "/index.html" {
+		// 站点首页 - 域名模式，base_path 为 /
+		s.renderSitePageWithBasePath(w, r, state, siteName, "login", basePath, origin)
+		return
+	}
+
+	// 匹配 *.html 页面
+	if strings.HasSuffix(urlPath, ".html") {
+		pageName := strings.TrimSuffix(strings.TrimPrefix(urlPath, "/"), ".html")
+		s.renderSitePageWithBasePath(w, r, state, siteName, pageName, basePath, origin)
+		return
+	}
+
+	http.NotFound(w, r)
+}
+
+// handleSiteAPIConfig 处理站点配置 API 请求
+func (s *Server) handleSiteAPIConfig(w http.ResponseWriter, r *http.Request, state *siteState, siteName string) {
+	config, exists := state.siteConfigs[siteName]
+	if !exists {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(config)
+}
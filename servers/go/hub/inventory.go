@@ -0,0 +1,520 @@
+package hub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// inventoryIDLayout 快照 ID（也是文件名）的时间格式，按字典序排列即按时间排列
+const inventoryIDLayout = "20060102T150405.000Z"
+
+// InventoryConfig 资产快照：定期为站点的表格执行跨地域聚合查询，把结果保存到磁盘，
+// 用于对比两次快照之间新增、删除和变化的行（按表格的 rowKey）
+type InventoryConfig struct {
+	Dir      string              // 快照目录，按 {site}/{table}/{id}.json 保存
+	Interval time.Duration       // 快照间隔，0 表示不启用
+	Retain   int                 // 每个表格保留的快照数，0 表示全部保留
+	Tables   map[string][]string // 站点名（"*" 为所有站点）到表格列表；为空时为所有配置了 product 和 rowKey 的表格
+}
+
+// Validate 检查配置是否可用
+func (c InventoryConfig) Validate() error {
+	if c.Interval < 0 || c.Retain < 0 {
+		return fmt.Errorf("interval and retain must not be negative")
+	}
+	if c.Interval > 0 && c.Interval < time.Minute {
+		return fmt.Errorf("interval must be at least 1m")
+	}
+	if c.Interval > 0 && c.Dir == "" {
+		return fmt.Errorf("dir is required")
+	}
+	return nil
+}
+
+// inventorySnapshot 一次快照，Regions 中失败或被截断的地域数据不完整
+type inventorySnapshot struct {
+	ID      string                   `json:"id"`
+	Site    string                   `json:"site"`
+	Table   string                   `json:"table"`
+	Time    time.Time                `json:"time"`
+	RowKey  string                   `json:"row_key"`
+	Regions []fanOutRegionResult     `json:"regions"`
+	Rows    []map[string]interface{} `json:"rows"`
+
+	// RegionParam 每一行中记录所属地域的字段名（签名算法的 RegionParam）
+	RegionParam string `json:"region_param"`
+}
+
+// inventorySnapshotInfo 快照列表中的一项
+type inventorySnapshotInfo struct {
+	ID   string    `json:"id"`
+	Time time.Time `json:"time"`
+}
+
+// inventoryDiff 两次快照之间的变化
+type inventoryDiff struct {
+	From    inventorySnapshotInfo    `json:"from"`
+	To      inventorySnapshotInfo    `json:"to"`
+	RowKey  string                   `json:"row_key"`
+	Added   []map[string]interface{} `json:"added"`
+	Removed []map[string]interface{} `json:"removed"`
+	Changed []inventoryChange        `json:"changed"`
+
+	// IncompleteRegions 任一快照中查询失败或被截断的地域，这些地域的行不计入新增和删除
+	IncompleteRegions []string `json:"incomplete_regions,omitempty"`
+
+	// RegionParam 每一行中记录所属地域的字段名
+	RegionParam string `json:"region_param"`
+}
+
+// inventoryChange 两次快照中都存在但字段有变化的行，Row 为新快照中的行
+type inventoryChange struct {
+	Key    string                 `json:"key"`
+	Row    map[string]interface{} `json:"row"`
+	Fields []inventoryFieldChange `json:"fields"`
+}
+
+// inventoryFieldChange 一个顶层字段的变化，字段不存在时为 null
+type inventoryFieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// inventoryTables 站点参与快照的表格，按名称排序；只包含配置了 product 和 rowKey 的表格
+func (s *Server) inventoryTables(config Config, siteName string) []string {
+	names, ok := s.inventory.Tables[siteName]
+	if !ok {
+		names, ok = s.inventory.Tables["*"]
+	}
+	if !ok {
+		tables, _ := config["tables"].(map[string]interface{})
+		for name := range tables {
+			names = append(names, name)
+		}
+	}
+
+	var result []string
+	for _, name := range names {
+		if table, ok := fanOutTableFor(config, name); ok && table.RowKey != "" {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// hasInventoryTable 表格是否参与快照
+func (s *Server) hasInventoryTable(config Config, siteName, table string) bool {
+	for _, name := range s.inventoryTables(config, siteName) {
+		if name == table {
+			return true
+		}
+	}
+	return false
+}
+
+// RunInventory 按 Inventory.Interval 定期生成资产快照，直到 ctx 结束；未启用时立即返回
+// 启动时距离最近一次快照已经超过一个间隔（或还没有快照）时立即生成一次
+func (s *Server) RunInventory(ctx context.Context) {
+	if s.inventory.Interval <= 0 {
+		return
+	}
+	wait := s.inventory.Interval - time.Since(s.latestInventory())
+	for {
+		timer := time.NewTimer(max(wait, 0))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.SnapshotInventory(ctx)
+		wait = s.inventory.Interval
+	}
+}
+
+// SnapshotInventory 立即为所有配置了凭证的站点生成一次快照，返回保存的快照数；
+// 同一时间只有一次快照在进行，其他调用等待它完成
+func (s *Server) SnapshotInventory(ctx context.Context) int {
+	s.inventoryMu.Lock()
+	defer s.inventoryMu.Unlock()
+	return s.snapshotInventory(ctx)
+}
+
+// snapshotInventory 生成快照，调用方持有 inventoryMu
+func (s *Server) snapshotInventory(ctx context.Context) int {
+	if s.inventory.Dir == "" || s.credentials == nil {
+		return 0
+	}
+	state := s.getState()
+	sites := make([]string, 0, len(state.siteConfigs))
+	for siteName := range state.siteConfigs {
+		sites = append(sites, siteName)
+	}
+	sort.Strings(sites)
+
+	saved := 0
+	for _, siteName := range sites {
+		credential, ok := s.credentials.Credential(siteName)
+		if !ok {
+			continue
+		}
+		config := state.siteConfigs[siteName]
+		for _, table := range s.inventoryTables(config, siteName) {
+			if ctx.Err() != nil {
+				return saved
+			}
+			snapshot, err := s.takeInventorySnapshot(ctx, config, siteName, credential, table)
+			if err == nil {
+				err = s.saveInventorySnapshot(snapshot)
+			}
+			if err != nil {
+				s.logger.Printf("[Inventory] %s/%s: %v", siteName, table, err)
+				continue
+			}
+			saved++
+			s.logger.Printf("[Inventory] %s/%s: saved snapshot %s (%d rows)", siteName, table, snapshot.ID, len(snapshot.Rows))
+		}
+	}
+	return saved
+}
+
+// takeInventorySnapshot 查询表格的全部地域；所有地域都失败时返回错误，不保存空快照
+func (s *Server) takeInventorySnapshot(ctx context.Context, config Config, siteName string, credential Credential, table string) (*inventorySnapshot, error) {
+	job, apiErr := s.newFanOutJob(config, siteName, credential, table)
+	if apiErr != nil {
+		return nil, fmt.Errorf("%s: %s", apiErr.Code, apiErr.Message)
+	}
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	items, results, apiErr := s.fanOutCollect(ctx, job, "")
+	if apiErr != nil {
+		return nil, fmt.Errorf("%s: %s", apiErr.Code, apiErr.Message)
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Code != "" && result.Count == 0 {
+			failed++
+		}
+	}
+	if failed == len(results) {
+		return nil, fmt.Errorf("all %d regions failed: %s: %s", failed, results[0].Code, results[0].Message)
+	}
+
+	snapshot := &inventorySnapshot{
+		ID:      now.Format(inventoryIDLayout),
+		Site:    siteName,
+		Table:   table,
+		Time:    now,
+		RowKey:  job.table.RowKey,
+		Regions: results,
+		Rows:    make([]map[string]interface{}, 0, len(items)),
+
+		RegionParam: job.regionParam,
+	}
+	for _, item := range items {
+		if row, ok := item.(map[string]interface{}); ok {
+			snapshot.Rows = append(snapshot.Rows, row)
+		}
+	}
+	return snapshot, nil
+}
+
+// inventoryDir 表格的快照目录
+func (s *Server) inventoryDir(siteName, table string) string {
+	return filepath.Join(s.inventory.Dir, siteName, table)
+}
+
+// saveInventorySnapshot 在快照目录中写入唯一的临时文件后重命名，然后删除超出 Retain 的旧快照
+func (s *Server) saveInventorySnapshot(snapshot *inventorySnapshot) error {
+	dir := s.inventoryDir(snapshot.Site, snapshot.Table)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomicPerm(filepath.Join(dir, snapshot.ID+".json"), data, 0600); err != nil {
+		return err
+	}
+
+	if s.inventory.Retain > 0 {
+		snapshots, err := s.listInventorySnapshots(snapshot.Site, snapshot.Table)
+		if err != nil {
+			return err
+		}
+		for _, old := range snapshots[min(s.inventory.Retain, len(snapshots)):] {
+			if err := os.Remove(filepath.Join(dir, old.ID+".json")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// listInventorySnapshots 表格的所有快照，最新的在前；目录不存在时为空
+func (s *Server) listInventorySnapshots(siteName, table string) ([]inventorySnapshotInfo, error) {
+	entries, err := os.ReadDir(s.inventoryDir(siteName, table))
+	if errors.Is(err, fs.ErrNotExist) {
+		return []inventorySnapshotInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	snapshots := []inventorySnapshotInfo{}
+	for i := len(entries) - 1; i >= 0; i-- {
+		id, ok := strings.CutSuffix(entries[i].Name(), ".json")
+		if !ok {
+			continue
+		}
+		if t, err := time.Parse(inventoryIDLayout, id); err == nil {
+			snapshots = append(snapshots, inventorySnapshotInfo{ID: id, Time: t})
+		}
+	}
+	return snapshots, nil
+}
+
+// loadInventorySnapshot 读取快照，数字保持为 json.Number，与查询时的值比较不会有精度差异
+func (s *Server) loadInventorySnapshot(siteName, table, id string) (*inventorySnapshot, error) {
+	if _, err := time.Parse(inventoryIDLayout, id); err != nil {
+		return nil, fs.ErrNotExist
+	}
+	data, err := os.ReadFile(filepath.Join(s.inventoryDir(siteName, table), id+".json"))
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var snapshot inventorySnapshot
+	if err := decoder.Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("%s: %w", id, err)
+	}
+	return &snapshot, nil
+}
+
+// latestInventory 所有参与快照的表格中最近一次快照的时间，没有快照时为零值
+func (s *Server) latestInventory() time.Time {
+	var latest time.Time
+	state := s.getState()
+	for siteName, config := range state.siteConfigs {
+		for _, table := range s.inventoryTables(config, siteName) {
+			snapshots, _ := s.listInventorySnapshots(siteName, table)
+			if len(snapshots) > 0 && snapshots[0].Time.After(latest) {
+				latest = snapshots[0].Time
+			}
+		}
+	}
+	return latest
+}
+
+// resolveInventoryDiff 按 ID 读取两次快照并对比；to 为空时为最新的快照，from 为空时为 to 的前一次快照
+func (s *Server) resolveInventoryDiff(siteName, table, fromID, toID string) (*inventoryDiff, *apiError) {
+	snapshots, err := s.listInventorySnapshots(siteName, table)
+	if err != nil {
+		s.logger.Printf("[Inventory] %s/%s: %v", siteName, table, err)
+		return nil, &apiError{http.StatusInternalServerError, "InternalError", "Failed to list snapshots"}
+	}
+	if toID == "" && len(snapshots) > 0 {
+		toID = snapshots[0].ID
+	}
+	if fromID == "" {
+		for i, snapshot := range snapshots {
+			if snapshot.ID == toID && i+1 < len(snapshots) {
+				fromID = snapshots[i+1].ID
+			}
+		}
+	}
+	if fromID == "" || toID == "" {
+		return nil, &apiError{http.StatusNotFound, "SnapshotNotFound", "At least two snapshots are required"}
+	}
+
+	var pair [2]*inventorySnapshot
+	for i, id := range []string{fromID, toID} {
+		snapshot, err := s.loadInventorySnapshot(siteName, table, id)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, &apiError{http.StatusNotFound, "SnapshotNotFound", "Snapshot not found: " + id}
+		}
+		if err != nil {
+			s.logger.Printf("[Inventory] %s/%s: %v", siteName, table, err)
+			return nil, &apiError{http.StatusInternalServerError, "InternalError", "Failed to read snapshot: " + id}
+		}
+		pair[i] = snapshot
+	}
+	return diffInventory(pair[0], pair[1]), nil
+}
+
+// diffInventory 按 rowKey 对比两次快照
+func diffInventory(from, to *inventorySnapshot) *inventoryDiff {
+	rowKey := to.RowKey
+	diff := &inventoryDiff{
+		From:    inventorySnapshotInfo{ID: from.ID, Time: from.Time},
+		To:      inventorySnapshotInfo{ID: to.ID, Time: to.Time},
+		RowKey:  rowKey,
+		Added:   []map[string]interface{}{},
+		Removed: []map[string]interface{}{},
+		Changed: []inventoryChange{},
+
+		RegionParam: to.RegionParam,
+	}
+
+	// 任一快照中不完整的地域：行的缺失可能只是没有查到
+	incomplete := make(map[string]bool)
+	for _, snapshot := range []*inventorySnapshot{from, to} {
+		for _, result := range snapshot.Regions {
+			if result.Code != "" || result.Truncated {
+				incomplete[result.Region] = true
+			}
+		}
+	}
+	for region := range incomplete {
+		diff.IncompleteRegions = append(diff.IncompleteRegions, region)
+	}
+	sort.Strings(diff.IncompleteRegions)
+	regionOf := func(row map[string]interface{}) string {
+		region, _ := row[to.RegionParam].(string)
+		return region
+	}
+
+	index := func(rows []map[string]interface{}) map[string]map[string]interface{} {
+		keyed := make(map[string]map[string]interface{}, len(rows))
+		for _, row := range rows {
+			if key := inventoryRowKey(row, rowKey); key != "" {
+				keyed[key] = row
+			}
+		}
+		return keyed
+	}
+	before, after := index(from.Rows), index(to.Rows)
+
+	for _, row := range to.Rows {
+		key := inventoryRowKey(row, rowKey)
+		if key == "" {
+			continue
+		}
+		old, existed := before[key]
+		if !existed {
+			if !incomplete[regionOf(row)] {
+				diff.Added = append(diff.Added, row)
+			}
+			continue
+		}
+		if fields := diffInventoryRow(old, row); len(fields) > 0 {
+			diff.Changed = append(diff.Changed, inventoryChange{Key: key, Row: row, Fields: fields})
+		}
+	}
+	for _, row := range from.Rows {
+		key := inventoryRowKey(row, rowKey)
+		if _, exists := after[key]; key != "" && !exists && !incomplete[regionOf(row)] {
+			diff.Removed = append(diff.Removed, row)
+		}
+	}
+	return diff
+}
+
+// inventoryRowKey 行的唯一键，没有该字段时为空
+func inventoryRowKey(row map[string]interface{}, rowKey string) string {
+	switch value := lookupPath(row, rowKey).(type) {
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	}
+	return ""
+}
+
+// diffInventoryRow 对比两行的顶层字段，按字段名排序
+func diffInventoryRow(old, row map[string]interface{}) []inventoryFieldChange {
+	names := make(map[string]bool, len(row))
+	for name := range old {
+		names[name] = true
+	}
+	for name := range row {
+		names[name] = true
+	}
+	var fields []inventoryFieldChange
+	for name := range names {
+		if !reflect.DeepEqual(old[name], row[name]) {
+			fields = append(fields, inventoryFieldChange{Field: name, From: old[name], To: row[name]})
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+	return fields
+}
+
+// handleAPIInventory 处理资产快照的查询接口：
+//
+//	GET /{site}/api/inventory/{table}                     快照列表，最新的在前
+//	GET /{site}/api/inventory/{table}/diff?from=&to=      两次快照之间新增、删除和变化的行，默认为最新的两次
+//	GET /{site}/api/inventory/{table}/{id}                一次快照的完整内容
+//
+// 快照由服务端凭证生成，和聚合查询一样只对配置了凭证的站点开放
+func (s *Server) handleAPIInventory(w http.ResponseWriter, r *http.Request, state *siteState, siteName, origin, table, sub string) {
+	if s.inventory.Dir == "" || s.credentials == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if _, ok := s.credentials.Credential(siteName); !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeAPIProxyError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "Only GET is allowed")
+		return
+	}
+	if _, ok := s.authenticateAPI(w, r, siteName, origin); !ok {
+		return
+	}
+	if !s.hasInventoryTable(state.siteConfigs[siteName], siteName, table) {
+		writeAPIProxyError(w, http.StatusNotFound, "UnknownTable", "Table is not configured for inventory: "+table)
+		return
+	}
+
+	var response interface{}
+	switch sub {
+	case "":
+		snapshots, err := s.listInventorySnapshots(siteName, table)
+		if err != nil {
+			s.logger.Printf("[Inventory] %s/%s: %v", siteName, table, err)
+			writeAPIProxyError(w, http.StatusInternalServerError, "InternalError", "Failed to list snapshots")
+			return
+		}
+		response = map[string]interface{}{"table": table, "snapshots": snapshots}
+	case "diff":
+		diff, apiErr := s.resolveInventoryDiff(siteName, table, r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+		if apiErr != nil {
+			writeAPIProxyError(w, apiErr.Status, apiErr.Code, apiErr.Message)
+			return
+		}
+		response = diff
+	default:
+		snapshot, err := s.loadInventorySnapshot(siteName, table, sub)
+		if errors.Is(err, fs.ErrNotExist) {
+			writeAPIProxyError(w, http.StatusNotFound, "SnapshotNotFound", "Snapshot not found: "+sub)
+			return
+		}
+		if err != nil {
+			s.logger.Printf("[Inventory] %s/%s: %v", siteName, table, err)
+			writeAPIProxyError(w, http.StatusInternalServerError, "InternalError", "Failed to read snapshot")
+			return
+		}
+		response = snapshot
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,129 @@
+package hub
+
+import "net/http"
+
+// inventoryColumnsFor 表格在资产变化页面中显示的列，第一列为所属地域
+func inventoryColumnsFor(config Config, table, regionParam string) []tableColumn {
+	columns, _ := tableColumnsFor(config, table)
+	return append([]tableColumn{{Label: "地域", Field: regionParam, DefaultValue: "-"}}, columns...)
+}
+
+// inventoryPageData 配置了 "inventory": true 的页面中的 inventory 变量：
+// 按查询参数 table、from、to 对比两次快照（默认为第一个表格的最新两次快照），
+// 值都已转换为文本，模板直接输出即可
+//
+//	enabled             是否启用了资产快照（服务端配置了快照目录和站点凭证）
+//	tables              [{key, title}] 参与快照的表格
+//	table               当前表格
+//	snapshots           [{id, time}] 当前表格的快照，最新的在前
+//	from, to            对比的两次快照 ID
+//	columns             表头
+//	added, removed      [{key, cells}] 新增和删除的行
+//	changed             [{key, cells, fields: [{field, from, to}]}] 有变化的行，cells 为新快照中的值
+//	incomplete_regions  查询不完整的地域，这些地域的新增和删除没有统计
+//	error               无法对比时的原因，包括没有登录的 API 用户
+func (s *Server) inventoryPageData(r *http.Request, state *siteState, siteName, origin string) map[string]interface{} {
+	data := map[string]interface{}{"enabled": false}
+	if s.inventory.Dir == "" || s.credentials == nil {
+		return data
+	}
+	if _, ok := s.credentials.Credential(siteName); !ok {
+		return data
+	}
+	config := state.siteConfigs[siteName]
+	names := s.inventoryTables(config, siteName)
+	if len(names) == 0 {
+		return data
+	}
+	data["enabled"] = true
+
+	configTables, _ := config["tables"].(map[string]interface{})
+	tables := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		entry, _ := configTables[name].(map[string]interface{})
+		title, _ := entry["title"].(string)
+		if title == "" {
+			title = name
+		}
+		tables = append(tables, map[string]interface{}{"key": name, "title": title})
+	}
+	data["tables"] = tables
+
+	// 快照内容和 API 接口一样只对已登录的用户显示
+	if _, apiErr := s.identifyAPIUser(r, siteName, origin); apiErr != nil {
+		data["error"] = "需要使用访问令牌登录后才能查看资产快照"
+		return data
+	}
+
+	query := r.URL.Query()
+	table := names[0]
+	if requested := query.Get("table"); s.hasInventoryTable(config, siteName, requested) {
+		table = requested
+	}
+	data["table"] = table
+
+	snapshots, err := s.listInventorySnapshots(siteName, table)
+	if err != nil {
+		s.logger.Printf("[Inventory] %s/%s: %v", siteName, table, err)
+		data["error"] = "读取快照列表失败"
+		return data
+	}
+	options := make([]map[string]interface{}, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		options = append(options, map[string]interface{}{
+			"id":   snapshot.ID,
+			"time": snapshot.Time.Format("2006-01-02 15:04:05 UTC"),
+		})
+	}
+	data["snapshots"] = options
+
+	diff, apiErr := s.resolveInventoryDiff(siteName, table, query.Get("from"), query.Get("to"))
+	if apiErr != nil {
+		if apiErr.Code == "SnapshotNotFound" && len(snapshots) < 2 {
+			data["error"] = "至少需要两次快照才能对比"
+		} else {
+			data["error"] = apiErr.Message
+		}
+		return data
+	}
+	data["from"], data["to"] = diff.From.ID, diff.To.ID
+	data["incomplete_regions"] = diff.IncompleteRegions
+
+	columns := inventoryColumnsFor(config, table, diff.RegionParam)
+	labels := make([]string, len(columns))
+	for i, column := range columns {
+		labels[i] = column.Label
+	}
+	data["columns"] = labels
+
+	cells := func(row map[string]interface{}) []string {
+		values := make([]string, len(columns))
+		for i, column := range columns {
+			values[i] = column.text(row)
+		}
+		return values
+	}
+	rows := func(items []map[string]interface{}) []map[string]interface{} {
+		result := make([]map[string]interface{}, 0, len(items))
+		for _, row := range items {
+			result = append(result, map[string]interface{}{"key": inventoryRowKey(row, diff.RowKey), "cells": cells(row)})
+		}
+		return result
+	}
+	data["added"], data["removed"] = rows(diff.Added), rows(diff.Removed)
+
+	changed := make([]map[string]interface{}, 0, len(diff.Changed))
+	for _, change := range diff.Changed {
+		fields := make([]map[string]interface{}, 0, len(change.Fields))
+		for _, field := range change.Fields {
+			fields = append(fields, map[string]interface{}{
+				"field": field.Field,
+				"from":  cellText(field.From, "-"),
+				"to":    cellText(field.To, "-"),
+			})
+		}
+		changed = append(changed, map[string]interface{}{"key": change.Key, "cells": cells(change.Row), "fields": fields})
+	}
+	data["changed"] = changed
+	return data
+}
@@ -0,0 +1,268 @@
+package hub
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	manageMaxValueLength   = 256     // form 类操作输入值的最大长度
+	manageMaxResponseBytes = 1 << 20 // 云服务 API 响应的最大字节数
+)
+
+// manageAction 站点配置 resource_manage.{resource}.actions[] 中可以由服务端执行的操作（带 api 定义）：
+//
+//	{"name": "release", "confirmMessage": "...", "api": {"product": "vpc", "action": "ReleaseEipAddress", "idParam": "AllocationId"}}
+type manageAction struct {
+	Product    string            // api.product，接口所属的产品
+	Action     string            // api.action，接口名
+	IDParam    string            // api.idParam，资源 ID 使用的参数名
+	InputParam string            // api.inputParam，form 类操作（例如重命名）的输入值使用的参数名，没有输入时为空
+	Params     map[string]string // api.params，固定的参数
+	Confirm    bool              // 配置了 confirmMessage，执行前需要确认令牌
+}
+
+// manageActionFor 读取站点配置中的操作定义
+func manageActionFor(config Config, resource, name string) (manageAction, bool) {
+	resources, _ := config["resource_manage"].(map[string]interface{})
+	entry, _ := resources[resource].(map[string]interface{})
+	actions, _ := entry["actions"].([]interface{})
+	for _, item := range actions {
+		fields, _ := item.(map[string]interface{})
+		if actionName, _ := fields["name"].(string); actionName != name {
+			continue
+		}
+		api, _ := fields["api"].(map[string]interface{})
+		var action manageAction
+		action.Product, _ = api["product"].(string)
+		action.Action, _ = api["action"].(string)
+		action.IDParam, _ = api["idParam"].(string)
+		action.InputParam, _ = api["inputParam"].(string)
+		if action.Product == "" || action.Action == "" || action.IDParam == "" {
+			return manageAction{}, false
+		}
+		params, _ := api["params"].(map[string]interface{})
+		action.Params = make(map[string]string, len(params))
+		for key, value := range params {
+			if s, ok := value.(string); ok {
+				action.Params[key] = s
+			}
+		}
+		message, _ := fields["confirmMessage"].(string)
+		action.Confirm = message != ""
+		return action, true
+	}
+	return manageAction{}, false
+}
+
+// isManagedAPI 接口是否被 resource_manage 中的某个操作使用；这些接口只能通过 api/manage/ 调用，
+// 签名代理拒绝直接调用，防止绕过策略和审计日志
+func isManagedAPI(config Config, product, action string) bool {
+	resources, _ := config["resource_manage"].(map[string]interface{})
+	for _, entry := range resources {
+		resource, _ := entry.(map[string]interface{})
+		actions, _ := resource["actions"].([]interface{})
+		for _, item := range actions {
+			fields, _ := item.(map[string]interface{})
+			api, _ := fields["api"].(map[string]interface{})
+			if api["product"] == product && api["action"] == action {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// handleAPIManage 处理 POST /{site}/api/manage/{resource}/{action}：
+// 按站点配置 resource_manage 中的操作定义，使用服务端凭证调用云服务 API。
+// 参数 region、resource_id 和 value（form 类操作的输入值）；执行前按策略放行或拒绝，
+// 需要确认的操作在没有有效的 confirm_token 时返回 428 和新的确认令牌，用户确认后带上令牌重新提交。
+// 调用者必须是已登录的 API 用户；每次放行、拒绝和要求确认都写入审计日志；站点没有配置凭证时返回 404
+func (s *Server) handleAPIManage(w http.ResponseWriter, r *http.Request, state *siteState, siteName, origin, resourceName, actionName string) {
+	if s.credentials == nil {
+		http.NotFound(w, r)
+		return
+	}
+	credential, ok := s.credentials.Credential(siteName)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		writeAPIProxyError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "Only POST is allowed")
+		return
+	}
+	user, ok := s.authenticateAPI(w, r, siteName, origin)
+	if !ok {
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeAPIProxyError(w, http.StatusBadRequest, "InvalidParameter", err.Error())
+		return
+	}
+
+	config := state.siteConfigs[siteName]
+	action, ok := manageActionFor(config, resourceName, actionName)
+	if !ok {
+		writeAPIProxyError(w, http.StatusNotFound, "UnknownAction", "Action is not configured: "+resourceName+"/"+actionName)
+		return
+	}
+	signerName := apiSignerName(config)
+	signer, ok := s.signers[signerName]
+	if !ok {
+		s.logger.Printf("[APIProxy] %s: unknown signer %q", siteName, signerName)
+		writeAPIProxyError(w, http.StatusInternalServerError, "UnknownSigner", "Signer is not available: "+signerName)
+		return
+	}
+
+	region, resourceID, value := r.Form.Get("region"), r.Form.Get("resource_id"), r.Form.Get("value")
+	switch {
+	case region == "" || !apiProxyRegionPattern.MatchString(region):
+		writeAPIProxyError(w, http.StatusBadRequest, "InvalidRegion", "Invalid region")
+		return
+	case resourceID == "" || len(resourceID) > manageMaxValueLength:
+		writeAPIProxyError(w, http.StatusBadRequest, "InvalidParameter", "Invalid resource_id")
+		return
+	case action.InputParam != "" && (value == "" || len(value) > manageMaxValueLength):
+		writeAPIProxyError(w, http.StatusBadRequest, "InvalidParameter", "Invalid value")
+		return
+	}
+
+	entry := AuditEntry{
+		Time:        time.Now(),
+		Site:        siteName,
+		User:        user.Name,
+		ClientIP:    ClientIP(r),
+		UserAgent:   r.UserAgent(),
+		Credential:  credential.Name,
+		AccessKeyID: maskAccessKeyID(credential.AccessKeyID),
+		Resource:    resourceName,
+		Action:      actionName,
+		API:         action.Product + "/" + action.Action,
+		Region:      region,
+		ResourceID:  resourceID,
+	}
+
+	effect := s.policy.decide(policyRequest{
+		Site:       siteName,
+		User:       user.Name,
+		Credential: credential.Name,
+		Resource:   resourceName,
+		Action:     actionName,
+		API:        entry.API,
+	})
+	if effect == PolicyDeny {
+		s.denyAudited(w, entry)
+		return
+	}
+	if action.Confirm || effect == PolicyConfirm {
+		subject := strings.Join([]string{siteName, user.Name, credential.Name, resourceName, actionName, region, resourceID, value}, "\n")
+		if !s.confirmAudited(w, r.Form.Get("confirm_token"), subject, entry) {
+			return
+		}
+	}
+
+	params := make(map[string]string, len(action.Params)+3)
+	for key, value := range action.Params {
+		params[key] = value
+	}
+	params[signer.RegionParam()] = region
+	params[action.IDParam] = resourceID
+	if action.InputParam != "" {
+		params[action.InputParam] = value
+	}
+
+	req, apiErr := s.newAPIRequest(r.Context(), config, siteName, credential, action.Product, action.Action, params, nil)
+	if apiErr != nil {
+		entry.Result, entry.Code, entry.Message = AuditFailed, apiErr.Code, apiErr.Message
+		s.audit(entry)
+		writeAPIProxyError(w, apiErr.Status, apiErr.Code, apiErr.Message)
+		return
+	}
+	s.doAudited(w, req, entry)
+}
+
+// denyAudited 记录被策略拒绝的操作并返回 403
+func (s *Server) denyAudited(w http.ResponseWriter, entry AuditEntry) {
+	entry.Result, entry.Code, entry.Message = AuditDenied, "PolicyDenied", "Action is denied by server policy"
+	s.audit(entry)
+	writeAPIProxyError(w, http.StatusForbidden, entry.Code, entry.Message)
+}
+
+// confirmAudited 校验确认令牌；没有有效的令牌时记录审计日志，返回 428 和绑定到 subject 的新令牌
+func (s *Server) confirmAudited(w http.ResponseWriter, token, subject string, entry AuditEntry) bool {
+	if token != "" && s.confirmTokens.verify(token, subject) {
+		return true
+	}
+	entry.Result, entry.Code, entry.Message = AuditConfirmationRequired, "ConfirmationRequired", "Action requires confirmation, resubmit with confirm_token"
+	s.audit(entry)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusPreconditionRequired)
+	json.NewEncoder(w).Encode(map[string]string{
+		"Code":         entry.Code,
+		"Message":      entry.Message,
+		"ConfirmToken": s.confirmTokens.issue(subject),
+	})
+	return false
+}
+
+// doAudited 发送已签名的请求，按云服务 API 的响应写入审计日志后原样返回响应
+func (s *Server) doAudited(w http.ResponseWriter, req *http.Request, entry AuditEntry) {
+	resp, err := s.apiClient.Do(req)
+	if err != nil {
+		s.logger.Printf("[APIProxy] %s %s: %v", entry.Site, entry.API, upstreamError(err))
+		entry.Result, entry.Code, entry.Message = AuditFailed, "ProxyError", "Upstream request failed"
+		s.audit(entry)
+		writeAPIProxyError(w, http.StatusBadGateway, entry.Code, entry.Message)
+		return
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, manageMaxResponseBytes))
+	if err != nil {
+		entry.Result, entry.Code, entry.Message = AuditFailed, "ProxyError", "Upstream response was interrupted"
+		s.audit(entry)
+		writeAPIProxyError(w, http.StatusBadGateway, entry.Code, entry.Message)
+		return
+	}
+
+	// 请求已经发出，结果以云服务 API 的响应为准
+	var doc map[string]interface{}
+	json.Unmarshal(body, &doc)
+	entry.RequestID, _ = doc["RequestId"].(string)
+	if entry.RequestID == "" {
+		entry.RequestID, _ = lookupPath(doc, "Response.RequestId").(string)
+	}
+	if code, message := apiResponseError(doc); code != "" || resp.StatusCode != http.StatusOK {
+		entry.Result, entry.Code, entry.Message = AuditFailed, code, message
+		if code == "" {
+			entry.Code = "UpstreamError"
+		}
+	} else {
+		entry.Result = AuditSuccess
+	}
+	s.audit(entry)
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(resp.StatusCode)
+	w.Write(body)
+}
+
+// audit 写入审计日志，没有配置审计日志时写入普通日志
+func (s *Server) audit(entry AuditEntry) {
+	if s.auditLog == nil {
+		s.logger.Printf("[Audit] %s %s %s %s %s/%s %s %s %s %s", entry.Site, entry.User, entry.Credential, entry.API, entry.Resource, entry.Action, entry.Region, entry.ResourceID, entry.Result, entry.Code)
+		return
+	}
+	if err := s.auditLog.Append(entry); err != nil {
+		s.logger.Printf("[Audit] Failed to append %s %s %s %s: %v", entry.Site, entry.User, entry.API, entry.ResourceID, err)
+	}
+}
@@ -0,0 +1,129 @@
+package hub
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memoryAuditLog 保存在内存中的审计日志
+type memoryAuditLog struct {
+	mu      sync.Mutex
+	entries []AuditEntry
+}
+
+func (l *memoryAuditLog) Append(entry AuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+func (l *memoryAuditLog) Query(query AuditQuery) ([]AuditEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var entries []AuditEntry
+	for i := len(l.entries) - 1; i >= 0; i-- {
+		if query.matches(l.entries[i]) {
+			entries = append(entries, l.entries[i])
+		}
+	}
+	return entries, nil
+}
+
+func (l *memoryAuditLog) last(t *testing.T) AuditEntry {
+	t.Helper()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.entries) == 0 {
+		t.Fatal("no audit entries")
+	}
+	return l.entries[len(l.entries)-1]
+}
+
+func TestPolicyAndAudit(t *testing.T) {
+	var calls []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Query().Get("Action"))
+		io.WriteString(w, `{"RequestId": "req-1"}`)
+	}))
+	defer upstream.Close()
+
+	auditLog := &memoryAuditLog{}
+	srv := newAPITestServer(t, upstream.URL, Options{
+		APIUsers: []APIUser{
+			{Name: "alice", TokenSHA256: HashAPIToken("alice-token")},
+			{Name: "bob", TokenSHA256: HashAPIToken("bob-token")},
+		},
+		Policy: Policy{Rules: []PolicyRule{
+			{User: "alice", Resource: "ecs_instance", Action: "stop", Effect: PolicyConfirm},
+			{User: "alice", API: "ecs/RunInstances", Effect: PolicyAllow},
+		}},
+		AuditLog: auditLog,
+	})
+
+	do := func(method, target, token string, form url.Values) (int, map[string]string) {
+		req := httptest.NewRequest(method, "http://example.com"+target, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		var body map[string]string
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		return rec.Code, body
+	}
+
+	// 没有规则匹配时默认拒绝
+	if status, _ := do(http.MethodGet, "/aliyun/api/proxy/ecs/RunInstances?RegionId=cn-hangzhou", "bob-token", nil); status != http.StatusForbidden {
+		t.Fatalf("proxy RunInstances as bob: status = %d, want 403", status)
+	}
+	if entry := auditLog.last(t); entry.User != "bob" || entry.Result != AuditDenied || entry.API != "ecs/RunInstances" {
+		t.Fatalf("audit entry = %+v", entry)
+	}
+
+	// 策略放行的非只读接口写入审计日志
+	if status, _ := do(http.MethodGet, "/aliyun/api/proxy/ecs/RunInstances?RegionId=cn-hangzhou", "alice-token", nil); status != http.StatusOK {
+		t.Fatalf("proxy RunInstances as alice: status = %d, want 200", status)
+	}
+	if entry := auditLog.last(t); entry.User != "alice" || entry.Result != AuditSuccess || entry.RequestID != "req-1" || entry.Region != "cn-hangzhou" {
+		t.Fatalf("audit entry = %+v", entry)
+	}
+
+	// 只读接口不经过策略，也不写审计日志
+	entries := len(auditLog.entries)
+	if status, _ := do(http.MethodGet, "/aliyun/api/proxy/ecs/DescribeInstances?RegionId=cn-hangzhou", "bob-token", nil); status != http.StatusOK {
+		t.Fatalf("proxy DescribeInstances: status = %d, want 200", status)
+	}
+	if len(auditLog.entries) != entries {
+		t.Fatalf("read-only action was audited")
+	}
+
+	// 需要确认的操作：第一次返回 428 并记录，带上令牌后执行
+	form := url.Values{"region": {"cn-hangzhou"}, "resource_id": {"i-1"}}
+	status, body := do(http.MethodPost, "/aliyun/api/manage/ecs_instance/stop", "alice-token", form)
+	if status != http.StatusPreconditionRequired || body["ConfirmToken"] == "" {
+		t.Fatalf("manage stop: status = %d, body = %v", status, body)
+	}
+	if entry := auditLog.last(t); entry.User != "alice" || entry.Result != AuditConfirmationRequired {
+		t.Fatalf("audit entry = %+v", entry)
+	}
+	// 确认令牌绑定用户，其他用户不能使用
+	form.Set("confirm_token", body["ConfirmToken"])
+	if status, _ := do(http.MethodPost, "/aliyun/api/manage/ecs_instance/stop", "bob-token", form); status != http.StatusForbidden {
+		t.Fatalf("manage stop as bob: status = %d, want 403", status)
+	}
+	if status, _ := do(http.MethodPost, "/aliyun/api/manage/ecs_instance/stop", "alice-token", form); status != http.StatusOK {
+		t.Fatalf("confirmed manage stop: status = %d, want 200", status)
+	}
+	if entry := auditLog.last(t); entry.Result != AuditSuccess || entry.ResourceID != "i-1" {
+		t.Fatalf("audit entry = %+v", entry)
+	}
+	if strings.Join(calls, ",") != "RunInstances,DescribeInstances,StopInstance" {
+		t.Fatalf("upstream calls = %v", calls)
+	}
+}
@@ -0,0 +1,14 @@
+package hub_test
+
+import (
+	"testing"
+
+	"github.com/firadio/jinja-hub/hub"
+	"github.com/firadio/jinja-hub/hub/ratelimittest"
+)
+
+func TestMemoryStore(t *testing.T) {
+	if err := ratelimittest.TestStore(hub.NewMemoryStore(0)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,409 @@
+package mockcloud
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// productVersions 各产品的 API 版本，请求的 Version 必须一致
+var productVersions = map[string]string{
+	"ecs": "2014-05-26",
+	"vpc": "2016-04-28",
+}
+
+// action 一个模拟的接口；handle 在持有锁时调用，返回的结果会加上 RequestId
+type action struct {
+	product string
+	handle  func(st *state, params map[string]string) (map[string]interface{}, *apiError)
+}
+
+// actions 站点 config.json 和 aliyun-api.js 用到的接口
+var actions = map[string]action{
+	"DescribeRegions":         {"ecs", describeRegions},
+	"DescribeInstances":       {"ecs", describeInstances},
+	"StartInstance":           {"ecs", startInstance},
+	"StopInstance":            {"ecs", stopInstance},
+	"RebootInstance":          {"ecs", rebootInstance},
+	"ModifyInstanceAttribute": {"ecs", modifyInstanceAttribute},
+	"DescribeInstanceVncUrl":  {"ecs", describeInstanceVncURL},
+	"DescribeDisks":           {"ecs", describeDisks},
+	"AttachDisk":              {"ecs", attachDisk},
+	"DetachDisk":              {"ecs", detachDisk},
+	"DeleteDisk":              {"ecs", deleteDisk},
+	"ModifyDiskAttribute":     {"ecs", modifyDiskAttribute},
+
+	"DescribeVpcs":              {"vpc", describeVpcs},
+	"DescribeVSwitches":         {"vpc", describeVSwitches},
+	"DescribeEipAddresses":      {"vpc", describeEipAddresses},
+	"AllocateEipAddress":        {"vpc", allocateEipAddress},
+	"ReleaseEipAddress":         {"vpc", releaseEipAddress},
+	"ModifyEipAddressAttribute": {"vpc", modifyEipAddressAttribute},
+	"DeletionProtection":        {"vpc", deletionProtection},
+}
+
+var (
+	errIncorrectInstanceStatus = &apiError{http.StatusForbidden, "IncorrectInstanceStatus", "The current status of the resource does not support this operation."}
+	errIncorrectDiskStatus     = &apiError{http.StatusForbidden, "IncorrectDiskStatus", "The current disk status does not support this operation."}
+	errIncorrectEipStatus      = &apiError{http.StatusForbidden, "IncorrectEipStatus", "The current status of the resource does not support this operation."}
+	errDeletionProtection      = &apiError{http.StatusForbidden, "OperationFailed.DeletionProtection", "The resource has deletion protection enabled and cannot be released."}
+)
+
+func describeRegions(st *state, params map[string]string) (map[string]interface{}, *apiError) {
+	return map[string]interface{}{"Regions": map[string]interface{}{"Region": st.regions}}, nil
+}
+
+func describeInstances(st *state, params map[string]string) (map[string]interface{}, *apiError) {
+	filters := map[string]string{
+		"InstanceIds":        kindInstances.idField,
+		"PrivateIpAddresses": "VpcAttributes.PrivateIpAddress.IpAddress",
+		"PublicIpAddresses":  "PublicIpAddress.IpAddress",
+		"EipAddresses":       "EipAddress.IpAddress",
+	}
+	lists := make(map[string][]string)
+	for param := range filters {
+		list, apiErr := listParam(params, param)
+		if apiErr != nil {
+			return nil, apiErr
+		}
+		if list != nil {
+			lists[param] = list
+		}
+	}
+
+	return st.describe(kindInstances, params, func(item resource) bool {
+		for param, list := range lists {
+			if !hasAny(item, filters[param], list) {
+				return false
+			}
+		}
+		return matchFilter(params, "InstanceName", item, "InstanceName", true) &&
+			matchFilter(params, "Status", item, "Status", false) &&
+			matchFilter(params, "ZoneId", item, "ZoneId", false) &&
+			matchFilter(params, "VpcId", item, "VpcAttributes.VpcId", false)
+	})
+}
+
+// setInstanceStatus 实例状态为 from 时改为 to
+func setInstanceStatus(st *state, params map[string]string, from, to string) (map[string]interface{}, *apiError) {
+	instance, apiErr := st.find(kindInstances, params, "InstanceId")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if instance["Status"] != from {
+		return nil, errIncorrectInstanceStatus
+	}
+	instance["Status"] = to
+	return nil, nil
+}
+
+func startInstance(st *state, params map[string]string) (map[string]interface{}, *apiError) {
+	return setInstanceStatus(st, params, "Stopped", "Running")
+}
+
+func stopInstance(st *state, params map[string]string) (map[string]interface{}, *apiError) {
+	return setInstanceStatus(st, params, "Running", "Stopped")
+}
+
+func rebootInstance(st *state, params map[string]string) (map[string]interface{}, *apiError) {
+	return setInstanceStatus(st, params, "Running", "Running")
+}
+
+func modifyInstanceAttribute(st *state, params map[string]string) (map[string]interface{}, *apiError) {
+	instance, apiErr := st.find(kindInstances, params, "InstanceId")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	setFields(instance, params, "InstanceName", "Description", "HostName")
+	return nil, nil
+}
+
+func describeInstanceVncURL(st *state, params map[string]string) (map[string]interface{}, *apiError) {
+	instance, apiErr := st.find(kindInstances, params, "InstanceId")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if instance["Status"] != "Running" {
+		return nil, errIncorrectInstanceStatus
+	}
+	return map[string]interface{}{"VncUrl": "ws://mock-cloud.invalid/vnc?instanceId=" + params["InstanceId"]}, nil
+}
+
+func describeDisks(st *state, params map[string]string) (map[string]interface{}, *apiError) {
+	diskIDs, apiErr := listParam(params, "DiskIds")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	return st.describe(kindDisks, params, func(item resource) bool {
+		return (diskIDs == nil || hasAny(item, kindDisks.idField, diskIDs)) &&
+			matchFilter(params, "DiskName", item, "DiskName", true) &&
+			matchFilter(params, "InstanceId", item, "InstanceId", false) &&
+			matchFilter(params, "Category", item, "Category", false) &&
+			matchFilter(params, "Status", item, "Status", false) &&
+			matchFilter(params, "DiskType", item, "Type", false) &&
+			matchFilter(params, "ZoneId", item, "ZoneId", false)
+	})
+}
+
+func attachDisk(st *state, params map[string]string) (map[string]interface{}, *apiError) {
+	disk, apiErr := st.find(kindDisks, params, "DiskId")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	instance, apiErr := st.find(kindInstances, params, "InstanceId")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if disk["Status"] != "Available" {
+		return nil, errIncorrectDiskStatus
+	}
+	if status := instance["Status"]; status != "Running" && status != "Stopped" {
+		return nil, errIncorrectInstanceStatus
+	}
+	if disk["ZoneId"] != instance["ZoneId"] {
+		return nil, &apiError{http.StatusForbidden, "InstanceDiskZoneMismatch", "The specified disk is not in the same zone as the instance."}
+	}
+
+	used := make(map[string]bool)
+	for _, item := range st.resources[kindDisks] {
+		if item["InstanceId"] == params["InstanceId"] {
+			used[stringField(item, "Device")] = true
+		}
+	}
+	device := ""
+	for c := 'b'; c <= 'z' && device == ""; c++ {
+		if candidate := "/dev/xvd" + string(c); !used[candidate] {
+			device = candidate
+		}
+	}
+	disk["Status"] = "In_use"
+	disk["InstanceId"] = params["InstanceId"]
+	disk["Device"] = device
+	disk["AttachedTime"] = now()
+	return nil, nil
+}
+
+func detachDisk(st *state, params map[string]string) (map[string]interface{}, *apiError) {
+	disk, apiErr := st.find(kindDisks, params, "DiskId")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if params["InstanceId"] == "" {
+		return nil, missingParameter("InstanceId")
+	}
+	if disk["Status"] != "In_use" || disk["InstanceId"] != params["InstanceId"] {
+		return nil, errIncorrectDiskStatus
+	}
+	if disk["Type"] == "system" {
+		return nil, &apiError{http.StatusForbidden, "InvalidDiskCategory.NotSupported", "The system disk cannot be detached."}
+	}
+	disk["Status"] = "Available"
+	disk["InstanceId"] = ""
+	disk["Device"] = ""
+	disk["DetachedTime"] = now()
+	return nil, nil
+}
+
+func deleteDisk(st *state, params map[string]string) (map[string]interface{}, *apiError) {
+	disk, apiErr := st.find(kindDisks, params, "DiskId")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if disk["Status"] != "Available" {
+		return nil, errIncorrectDiskStatus
+	}
+	st.remove(kindDisks, disk)
+	return nil, nil
+}
+
+func modifyDiskAttribute(st *state, params map[string]string) (map[string]interface{}, *apiError) {
+	disk, apiErr := st.find(kindDisks, params, "DiskId")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	setFields(disk, params, "DiskName", "Description")
+	if value, ok := params["DeleteWithInstance"]; ok {
+		disk["DeleteWithInstance"] = value == "true"
+	}
+	return nil, nil
+}
+
+func describeVpcs(st *state, params map[string]string) (map[string]interface{}, *apiError) {
+	return st.describe(kindVpcs, params, func(item resource) bool {
+		return matchFilter(params, "VpcId", item, "VpcId", false) &&
+			matchFilter(params, "VpcName", item, "VpcName", true)
+	})
+}
+
+func describeVSwitches(st *state, params map[string]string) (map[string]interface{}, *apiError) {
+	return st.describe(kindVSwitches, params, func(item resource) bool {
+		return matchFilter(params, "VpcId", item, "VpcId", false) &&
+			matchFilter(params, "VSwitchId", item, "VSwitchId", false) &&
+			matchFilter(params, "VSwitchName", item, "VSwitchName", true) &&
+			matchFilter(params, "ZoneId", item, "ZoneId", false)
+	})
+}
+
+func describeEipAddresses(st *state, params map[string]string) (map[string]interface{}, *apiError) {
+	return st.describe(kindEips, params, func(item resource) bool {
+		return matchFilter(params, "AllocationId", item, "AllocationId", false) &&
+			matchFilter(params, "EipAddress", item, "IpAddress", false) &&
+			matchFilter(params, "EipName", item, "Name", true) &&
+			matchFilter(params, "AssociatedInstanceId", item, "InstanceId", false) &&
+			matchFilter(params, "Status", item, "Status", false)
+	})
+}
+
+func allocateEipAddress(st *state, params map[string]string) (map[string]interface{}, *apiError) {
+	// ClientToken 相同的请求返回之前申请的 EIP
+	if token := params["ClientToken"]; token != "" {
+		if id, ok := st.clientToken[token]; ok {
+			for _, item := range st.resources[kindEips] {
+				if item["AllocationId"] == id {
+					return allocateResult(item), nil
+				}
+			}
+		}
+	}
+
+	bandwidth := params["Bandwidth"]
+	if bandwidth == "" {
+		bandwidth = "5"
+	}
+	if _, apiErr := intParam(params, "Bandwidth", 5, 1, 500); apiErr != nil {
+		return nil, apiErr
+	}
+	chargeType := "PostPaid"
+	if params["InstanceChargeType"] == "PrePaid" {
+		chargeType = "PrePaid"
+	}
+	eip := resource{
+		"AllocationId":       newResourceID("eip-"),
+		"IpAddress":          newIPAddress(),
+		"RegionId":           params["RegionId"],
+		"Name":               params["Name"],
+		"Description":        params["Description"],
+		"Bandwidth":          bandwidth,
+		"InternetChargeType": defaultString(params["InternetChargeType"], "PayByBandwidth"),
+		"ChargeType":         chargeType,
+		"ISP":                defaultString(params["ISP"], "BGP"),
+		"Status":             "Available",
+		"InstanceId":         "",
+		"InstanceType":       "",
+		"DeletionProtection": false,
+		"ResourceGroupId":    params["ResourceGroupId"],
+		"AllocationTime":     now(),
+	}
+	st.resources[kindEips] = append(st.resources[kindEips], eip)
+	if token := params["ClientToken"]; token != "" {
+		st.clientToken[token] = eip["AllocationId"].(string)
+	}
+	return allocateResult(eip), nil
+}
+
+// allocateResult AllocateEipAddress 的响应
+func allocateResult(eip resource) map[string]interface{} {
+	return map[string]interface{}{
+		"AllocationId":    eip["AllocationId"],
+		"EipAddress":      eip["IpAddress"],
+		"ResourceGroupId": eip["ResourceGroupId"],
+	}
+}
+
+func releaseEipAddress(st *state, params map[string]string) (map[string]interface{}, *apiError) {
+	eip, apiErr := st.find(kindEips, params, "AllocationId")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if eip["DeletionProtection"] == true {
+		return nil, errDeletionProtection
+	}
+	if eip["Status"] != "Available" {
+		return nil, errIncorrectEipStatus
+	}
+	st.remove(kindEips, eip)
+	return nil, nil
+}
+
+func modifyEipAddressAttribute(st *state, params map[string]string) (map[string]interface{}, *apiError) {
+	eip, apiErr := st.find(kindEips, params, "AllocationId")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if _, ok := params["Bandwidth"]; ok {
+		if _, apiErr := intParam(params, "Bandwidth", 0, 1, 500); apiErr != nil {
+			return nil, apiErr
+		}
+	}
+	setFields(eip, params, "Name", "Description", "Bandwidth")
+	return nil, nil
+}
+
+// deletionProtection 设置删除保护，模拟服务只支持 EIP（Type 为 EIP，InstanceId 为 AllocationId）
+func deletionProtection(st *state, params map[string]string) (map[string]interface{}, *apiError) {
+	if params["Type"] != "EIP" {
+		return nil, invalidParameter("Type")
+	}
+	if params["ProtectionEnable"] != "true" && params["ProtectionEnable"] != "false" {
+		return nil, invalidParameter("ProtectionEnable")
+	}
+	lookup := map[string]string{"RegionId": params["RegionId"], "AllocationId": params["InstanceId"]}
+	eip, apiErr := st.find(kindEips, lookup, "AllocationId")
+	if apiErr != nil {
+		if apiErr.Code == "MissingAllocationId" {
+			apiErr = missingParameter("InstanceId")
+		}
+		return nil, apiErr
+	}
+	eip["DeletionProtection"] = params["ProtectionEnable"] == "true"
+	return nil, nil
+}
+
+// matchFilter 请求没有该筛选参数时匹配；wildcard 为 true 时支持 * 通配符
+func matchFilter(params map[string]string, param string, item resource, path string, wildcard bool) bool {
+	want, ok := params[param]
+	if !ok || want == "" {
+		return true
+	}
+	if wildcard {
+		return matchName(want, stringField(item, path))
+	}
+	return stringField(item, path) == want
+}
+
+// setFields 用请求中出现的参数更新同名字段
+func setFields(item resource, params map[string]string, names ...string) {
+	for _, name := range names {
+		if value, ok := params[name]; ok {
+			item[name] = value
+		}
+	}
+}
+
+func defaultString(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// now 与真实 API 相同格式的 UTC 时间
+func now() string {
+	return time.Now().UTC().Format("2006-01-02T15:04:05Z")
+}
+
+// newResourceID 随机的资源 ID，例如 eip-2ze5f7c3d1a9b8e4f6a0
+func newResourceID(prefix string) string {
+	var b [10]byte
+	rand.Read(b[:])
+	return prefix + hex.EncodeToString(b[:])
+}
+
+// newIPAddress 随机的公网 IP
+func newIPAddress() string {
+	var b [3]byte
+	rand.Read(b[:])
+	return fmt.Sprintf("47.%d.%d.%d", 96+b[0]%32, b[1], 1+b[2]%254)
+}
@@ -0,0 +1,303 @@
+// Package mockcloud 模拟阿里云 ECS 和 VPC 的 RPC 风格 OpenAPI，用于没有真实账号时的离线开发和测试
+//
+// 请求的签名按阿里云的规则校验（与前端 aliyun-api.js 的 generateSignature 和 hub.SignAliyunRPC 相同），
+// 资源从 JSON 夹具加载后保存在内存中，查询和修改操作的响应结构与真实 API 一致：
+//
+//	srv, err := mockcloud.New(mockcloud.Options{})
+//	ts := httptest.NewServer(srv)
+//	// 站点 config.json 的 api.ecs.endpoint / api.vpc.endpoint 指向 ts.URL + "/"，
+//	// 使用 mockcloud.DefaultAccessKeyID / DefaultAccessKeySecret 调用
+package mockcloud
+
+import (
+	"crypto/rand"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/firadio/jinja-hub/hub"
+)
+
+// 默认的模拟凭证
+const (
+	DefaultAccessKeyID     = "mock-access-key-id"
+	DefaultAccessKeySecret = "mock-access-key-secret"
+)
+
+// timestampWindow 请求的 Timestamp 与服务器时间允许的最大偏差，SignatureNonce 在此期间内不能重复使用
+const timestampWindow = 15 * time.Minute
+
+//go:embed fixtures/*.json
+var builtinFixtures embed.FS
+
+// Options 模拟服务配置
+type Options struct {
+	// AccessKeyID / AccessKeySecret 接受的凭证，为空时使用 DefaultAccessKeyID / DefaultAccessKeySecret
+	AccessKeyID     string
+	AccessKeySecret string
+
+	// Fixtures 夹具目录，包含 regions.json、instances.json、vpcs.json、vswitches.json、eips.json、disks.json，
+	// 每个文件是与真实 API 响应中列表项结构相同的 JSON 数组，缺少的文件视为空列表；为 nil 时使用内置夹具
+	Fixtures fs.FS
+
+	// Logger 记录每个请求，为 nil 时不记录
+	Logger *log.Logger
+}
+
+// Server 模拟的阿里云 OpenAPI，实现 http.Handler
+// 请求的路径和 Host 不影响处理，ECS 和 VPC 的接口按 Action 区分，可以共用一个 endpoint
+type Server struct {
+	accessKeyID     string
+	accessKeySecret string
+	fixtures        fs.FS
+	logger          *log.Logger
+
+	mu     sync.Mutex
+	state  *state
+	nonces map[string]time.Time // 时间窗口内用过的 SignatureNonce
+}
+
+// New 创建模拟服务并加载夹具
+func New(opts Options) (*Server, error) {
+	s := &Server{
+		accessKeyID:     opts.AccessKeyID,
+		accessKeySecret: opts.AccessKeySecret,
+		fixtures:        opts.Fixtures,
+		logger:          opts.Logger,
+		nonces:          make(map[string]time.Time),
+	}
+	if s.accessKeyID == "" {
+		s.accessKeyID = DefaultAccessKeyID
+	}
+	if s.accessKeySecret == "" {
+		s.accessKeySecret = DefaultAccessKeySecret
+	}
+	if s.fixtures == nil {
+		s.fixtures, _ = fs.Sub(builtinFixtures, "fixtures")
+	}
+	if err := s.Reset(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reset 重新加载夹具，丢弃之前的所有修改
+func (s *Server) Reset() error {
+	st, err := loadState(s.fixtures)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.state = st
+	s.mu.Unlock()
+	return nil
+}
+
+// ServeHTTP 处理 API 请求，以及 POST /_mock/reset（重新加载夹具）和 GET /_mock/state（当前的全部资源）
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// 浏览器中的 aliyun-api.js 跨域直接调用
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/_mock/reset":
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := s.Reset(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case "/_mock/state":
+		s.mu.Lock()
+		data, err := json.MarshalIndent(s.state.snapshot(), "", "  ")
+		s.mu.Unlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+		return
+	}
+
+	status, data := s.handleAPI(r, newRequestID())
+	if s.logger != nil {
+		s.logger.Printf("[MockCloud] %s %s %s %d", r.Method, r.FormValue("Action"), r.FormValue("RegionId"), status)
+	}
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// handleAPI 校验公共参数和签名后执行接口，返回状态码和 JSON 响应
+func (s *Server) handleAPI(r *http.Request, requestID string) (int, []byte) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		return errorResponse(requestID, "", errUnsupportedMethod)
+	}
+	if err := r.ParseForm(); err != nil {
+		return errorResponse(requestID, "", invalidParameter("query"))
+	}
+	params := make(map[string]string, len(r.Form))
+	for key, values := range r.Form {
+		if len(values) > 0 {
+			params[key] = values[0]
+		}
+	}
+
+	if apiErr := s.verify(r.Method, params); apiErr != nil {
+		return errorResponse(requestID, "", apiErr)
+	}
+
+	action, ok := actions[params["Action"]]
+	if !ok {
+		return errorResponse(requestID, "", errActionNotFound)
+	}
+	if params["Version"] != productVersions[action.product] {
+		return errorResponse(requestID, action.product, errInvalidVersion)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if params["Action"] != "DescribeRegions" {
+		if apiErr := s.state.checkRegion(params); apiErr != nil {
+			return errorResponse(requestID, action.product, apiErr)
+		}
+	}
+	result, apiErr := action.handle(s.state, params)
+	if apiErr != nil {
+		return errorResponse(requestID, action.product, apiErr)
+	}
+	if result == nil {
+		result = make(map[string]interface{})
+	}
+	result["RequestId"] = requestID
+	// 响应中引用了资源本身，在锁内编码
+	data, err := json.Marshal(result)
+	if err != nil {
+		return errorResponse(requestID, action.product, errInternal)
+	}
+	return http.StatusOK, data
+}
+
+// verify 校验凭证、签名、时间戳和 SignatureNonce
+func (s *Server) verify(method string, params map[string]string) *apiError {
+	for _, name := range []string{"Action", "Version", "AccessKeyId", "Signature", "SignatureMethod", "SignatureVersion", "SignatureNonce", "Timestamp"} {
+		if params[name] == "" {
+			return missingParameter(name)
+		}
+	}
+	if params["AccessKeyId"] != s.accessKeyID {
+		return errAccessKeyNotFound
+	}
+	if params["SignatureMethod"] != "HMAC-SHA1" {
+		return invalidParameter("SignatureMethod")
+	}
+	if params["SignatureVersion"] != "1.0" {
+		return invalidParameter("SignatureVersion")
+	}
+	if format, ok := params["Format"]; ok && !strings.EqualFold(format, "JSON") {
+		return invalidParameter("Format")
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, params["Timestamp"])
+	if err != nil {
+		return errTimestampFormat
+	}
+	now := time.Now()
+	if timestamp.Before(now.Add(-timestampWindow)) || timestamp.After(now.Add(timestampWindow)) {
+		return errTimestampExpired
+	}
+
+	signature := params["Signature"]
+	unsigned := make(map[string]string, len(params)-1)
+	for key, value := range params {
+		if key != "Signature" {
+			unsigned[key] = value
+		}
+	}
+	if hub.SignAliyunRPC(method, unsigned, s.accessKeySecret) != signature {
+		return &apiError{http.StatusBadRequest, "SignatureDoesNotMatch",
+			"Specified signature is not matched with our calculation. server string to sign is:" + hub.AliyunRPCStringToSign(method, unsigned)}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for nonce, seen := range s.nonces {
+		if now.Sub(seen) > timestampWindow {
+			delete(s.nonces, nonce)
+		}
+	}
+	if _, used := s.nonces[params["SignatureNonce"]]; used {
+		return errNonceUsed
+	}
+	s.nonces[params["SignatureNonce"]] = now
+	return nil
+}
+
+// apiError 阿里云 API 的错误响应
+type apiError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+var (
+	errUnsupportedMethod = &apiError{http.StatusBadRequest, "UnsupportedHTTPMethod", "This http method is not supported."}
+	errActionNotFound    = &apiError{http.StatusNotFound, "InvalidAction.NotFound", "Specified api is not found, please check your url and method."}
+	errInvalidVersion    = &apiError{http.StatusBadRequest, "InvalidVersion", "Specified parameter Version is not valid."}
+	errAccessKeyNotFound = &apiError{http.StatusNotFound, "InvalidAccessKeyId.NotFound", "Specified access key is not found."}
+	errTimestampFormat   = &apiError{http.StatusBadRequest, "InvalidTimeStamp.Format", "Specified time stamp or date value is not well formatted."}
+	errTimestampExpired  = &apiError{http.StatusBadRequest, "InvalidTimeStamp.Expired", "Specified time stamp or date value is expired."}
+	errNonceUsed         = &apiError{http.StatusBadRequest, "SignatureNonceUsed", "Specified signature nonce was used already."}
+	errInternal          = &apiError{http.StatusInternalServerError, "InternalError", "The request processing has failed due to some unknown error."}
+)
+
+// missingParameter 缺少必填参数，错误码与真实 API 相同，例如 MissingInstanceId
+func missingParameter(name string) *apiError {
+	return &apiError{http.StatusBadRequest, "Missing" + name, name + " is mandatory for this action."}
+}
+
+// invalidParameter 参数的值不合法
+func invalidParameter(name string) *apiError {
+	return &apiError{http.StatusBadRequest, "InvalidParameter", "The specified parameter " + name + " is not valid."}
+}
+
+// errorResponse 阿里云 API 格式的错误响应，HostId 为产品的 endpoint
+func errorResponse(requestID, product string, apiErr *apiError) (int, []byte) {
+	host := "aliyuncs.com"
+	if product != "" {
+		host = product + ".aliyuncs.com"
+	}
+	data, _ := json.Marshal(map[string]string{
+		"RequestId": requestID,
+		"HostId":    host,
+		"Code":      apiErr.Code,
+		"Message":   apiErr.Message,
+	})
+	return apiErr.Status, data
+}
+
+// newRequestID 与真实 API 格式相同的 RequestId（大写的 UUID）
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	h := strings.ToUpper(hex.EncodeToString(b[:]))
+	return fmt.Sprintf("%s-%s-%s-%s-%s", h[:8], h[8:12], h[12:16], h[16:20], h[20:])
+}
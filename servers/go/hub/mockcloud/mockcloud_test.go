@@ -0,0 +1,137 @@
+package mockcloud_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/firadio/jinja-hub/hub"
+	"github.com/firadio/jinja-hub/hub/mockcloud"
+)
+
+var nonceCounter atomic.Int64
+
+// signedParams 按 hub.SignAliyunRPC 签名的公共参数和接口参数
+func signedParams(action, version, secret string, extra map[string]string) map[string]string {
+	params := map[string]string{
+		"Action":           action,
+		"Version":          version,
+		"Format":           "JSON",
+		"AccessKeyId":      mockcloud.DefaultAccessKeyID,
+		"SignatureMethod":  "HMAC-SHA1",
+		"SignatureVersion": "1.0",
+		"SignatureNonce":   fmt.Sprintf("nonce-%d", nonceCounter.Add(1)),
+		"Timestamp":        time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+	}
+	for key, value := range extra {
+		params[key] = value
+	}
+	params["Signature"] = hub.SignAliyunRPC(http.MethodGet, params, secret)
+	return params
+}
+
+// call 发送 GET 请求并解码 JSON 响应
+func call(t *testing.T, srv http.Handler, params map[string]string) (int, map[string]interface{}) {
+	t.Helper()
+	query := url.Values{}
+	for key, value := range params {
+		query.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?"+query.Encode(), nil))
+	var body map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode response %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, body
+}
+
+func newServer(t *testing.T) *mockcloud.Server {
+	t.Helper()
+	srv, err := mockcloud.New(mockcloud.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+func TestSignatureVerification(t *testing.T) {
+	srv := newServer(t)
+	region := map[string]string{"RegionId": "cn-hangzhou"}
+
+	status, body := call(t, srv, signedParams("DescribeInstances", "2014-05-26", mockcloud.DefaultAccessKeySecret, region))
+	if status != http.StatusOK || body["TotalCount"] == nil {
+		t.Fatalf("signed request: %d %v", status, body)
+	}
+
+	status, body = call(t, srv, signedParams("DescribeInstances", "2014-05-26", "wrong-secret", region))
+	if status != http.StatusBadRequest || body["Code"] != "SignatureDoesNotMatch" {
+		t.Fatalf("wrong secret: %d %v", status, body)
+	}
+
+	replayed := signedParams("DescribeInstances", "2014-05-26", mockcloud.DefaultAccessKeySecret, region)
+	if status, body := call(t, srv, replayed); status != http.StatusOK {
+		t.Fatalf("first use of nonce: %d %v", status, body)
+	}
+	status, body = call(t, srv, replayed)
+	if status != http.StatusBadRequest || body["Code"] != "SignatureNonceUsed" {
+		t.Fatalf("replayed nonce: %d %v", status, body)
+	}
+
+	expired := map[string]string{"RegionId": "cn-hangzhou", "Timestamp": time.Now().Add(-time.Hour).UTC().Format("2006-01-02T15:04:05Z")}
+	status, body = call(t, srv, signedParams("DescribeInstances", "2014-05-26", mockcloud.DefaultAccessKeySecret, expired))
+	if status != http.StatusBadRequest || body["Code"] != "InvalidTimeStamp.Expired" {
+		t.Fatalf("expired timestamp: %d %v", status, body)
+	}
+}
+
+// eipProtection 查询 EIP 的 DeletionProtection
+func eipProtection(t *testing.T, srv http.Handler, allocationID string) interface{} {
+	t.Helper()
+	status, body := call(t, srv, signedParams("DescribeEipAddresses", "2016-04-28", mockcloud.DefaultAccessKeySecret,
+		map[string]string{"RegionId": "cn-hangzhou", "AllocationId": allocationID}))
+	if status != http.StatusOK {
+		t.Fatalf("DescribeEipAddresses: %d %v", status, body)
+	}
+	eips := body["EipAddresses"].(map[string]interface{})["EipAddress"].([]interface{})
+	if len(eips) != 1 {
+		t.Fatalf("DescribeEipAddresses returned %d items", len(eips))
+	}
+	return eips[0].(map[string]interface{})["DeletionProtection"]
+}
+
+func TestDeletionProtectionAndReset(t *testing.T) {
+	srv := newServer(t)
+	const eip = "eip-bp1mockeip000000002"
+
+	if got := eipProtection(t, srv, eip); got != false {
+		t.Fatalf("fixture DeletionProtection = %v, want false", got)
+	}
+	status, body := call(t, srv, signedParams("DeletionProtection", "2016-04-28", mockcloud.DefaultAccessKeySecret,
+		map[string]string{"RegionId": "cn-hangzhou", "Type": "EIP", "InstanceId": eip, "ProtectionEnable": "true"}))
+	if status != http.StatusOK {
+		t.Fatalf("DeletionProtection: %d %v", status, body)
+	}
+	if got := eipProtection(t, srv, eip); got != true {
+		t.Fatalf("DeletionProtection after enabling = %v, want true", got)
+	}
+	status, body = call(t, srv, signedParams("ReleaseEipAddress", "2016-04-28", mockcloud.DefaultAccessKeySecret,
+		map[string]string{"RegionId": "cn-hangzhou", "AllocationId": eip}))
+	if status != http.StatusForbidden || body["Code"] != "OperationFailed.DeletionProtection" {
+		t.Fatalf("ReleaseEipAddress with protection: %d %v", status, body)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/_mock/reset", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("reset: %d", rec.Code)
+	}
+	if got := eipProtection(t, srv, eip); got != false {
+		t.Fatalf("DeletionProtection after reset = %v, want false", got)
+	}
+}
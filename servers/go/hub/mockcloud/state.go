@@ -0,0 +1,269 @@
+package mockcloud
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// resource 一个资源，结构与真实 API 响应中的列表项相同
+type resource = map[string]interface{}
+
+// kind 资源类型：夹具文件、ID 字段和列表在响应中的位置（例如 Instances.Instance）
+type kind struct {
+	file        string
+	idField     string
+	listKey     string
+	itemKey     string
+	maxPageSize int
+}
+
+var (
+	kindInstances = &kind{"instances.json", "InstanceId", "Instances", "Instance", 100}
+	kindVpcs      = &kind{"vpcs.json", "VpcId", "Vpcs", "Vpc", 50}
+	kindVSwitches = &kind{"vswitches.json", "VSwitchId", "VSwitches", "VSwitch", 50}
+	kindEips      = &kind{"eips.json", "AllocationId", "EipAddresses", "EipAddress", 100}
+	kindDisks     = &kind{"disks.json", "DiskId", "Disks", "Disk", 100}
+
+	kinds = []*kind{kindInstances, kindVpcs, kindVSwitches, kindEips, kindDisks}
+)
+
+// state 内存中的全部资源，由 Server.mu 保护
+type state struct {
+	regions     []resource
+	resources   map[*kind][]resource
+	clientToken map[string]string // AllocateEipAddress 的 ClientToken 到 AllocationId，保证幂等
+}
+
+// loadState 从夹具目录加载资源；没有 regions.json 时地域取自资源的 RegionId
+func loadState(fixtures fs.FS) (*state, error) {
+	st := &state{resources: make(map[*kind][]resource), clientToken: make(map[string]string)}
+	for _, k := range kinds {
+		items, err := readFixture(fixtures, k.file)
+		if err != nil {
+			return nil, err
+		}
+		for i, item := range items {
+			if id, _ := item[k.idField].(string); id == "" {
+				return nil, fmt.Errorf("mockcloud: %s: item %d has no %s", k.file, i, k.idField)
+			}
+			if region, _ := item["RegionId"].(string); region == "" {
+				return nil, fmt.Errorf("mockcloud: %s: item %d has no RegionId", k.file, i)
+			}
+		}
+		st.resources[k] = items
+	}
+
+	regions, err := readFixture(fixtures, "regions.json")
+	if err != nil {
+		return nil, err
+	}
+	if regions == nil {
+		seen := make(map[string]bool)
+		for _, k := range kinds {
+			for _, item := range st.resources[k] {
+				if region := item["RegionId"].(string); !seen[region] {
+					seen[region] = true
+					regions = append(regions, resource{"RegionId": region, "LocalName": region, "Status": "available"})
+				}
+			}
+		}
+	}
+	for i, region := range regions {
+		if id, _ := region["RegionId"].(string); id == "" {
+			return nil, fmt.Errorf("mockcloud: regions.json: item %d has no RegionId", i)
+		}
+	}
+	st.regions = regions
+	return st, nil
+}
+
+// readFixture 读取一个夹具文件，文件不存在时返回 nil
+func readFixture(fixtures fs.FS, name string) ([]resource, error) {
+	data, err := fs.ReadFile(fixtures, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mockcloud: %w", err)
+	}
+	// 数字保持原样（json.Number），响应中的整数不会变成浮点数
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var items []resource
+	if err := decoder.Decode(&items); err != nil {
+		return nil, fmt.Errorf("mockcloud: %s: %w", name, err)
+	}
+	if items == nil {
+		items = []resource{}
+	}
+	return items, nil
+}
+
+// snapshot 当前的全部资源，按夹具文件名组织
+func (st *state) snapshot() map[string]interface{} {
+	result := map[string]interface{}{"regions.json": st.regions}
+	for _, k := range kinds {
+		result[k.file] = st.resources[k]
+	}
+	return result
+}
+
+// checkRegion 检查 RegionId 参数
+func (st *state) checkRegion(params map[string]string) *apiError {
+	region := params["RegionId"]
+	if region == "" {
+		return missingParameter("RegionId")
+	}
+	for _, item := range st.regions {
+		if item["RegionId"] == region {
+			return nil
+		}
+	}
+	return &apiError{http.StatusNotFound, "InvalidRegionId.NotFound", "The specified RegionId does not exist."}
+}
+
+// find 按 ID 查找地域中的资源，参数缺失或资源不存在时返回与真实 API 相同的错误
+func (st *state) find(k *kind, params map[string]string, param string) (resource, *apiError) {
+	id := params[param]
+	if id == "" {
+		return nil, missingParameter(param)
+	}
+	for _, item := range st.resources[k] {
+		if item[k.idField] == id && item["RegionId"] == params["RegionId"] {
+			return item, nil
+		}
+	}
+	return nil, &apiError{http.StatusNotFound, "Invalid" + k.idField + ".NotFound", "The specified " + k.idField + " does not exist."}
+}
+
+// remove 删除资源
+func (st *state) remove(k *kind, target resource) {
+	items := st.resources[k]
+	for i, item := range items {
+		if item[k.idField] == target[k.idField] {
+			st.resources[k] = append(items[:i:i], items[i+1:]...)
+			return
+		}
+	}
+}
+
+// describe 按地域和 match 筛选资源并分页，响应结构为 {TotalCount, PageNumber, PageSize, listKey: {itemKey: [...]}}
+func (st *state) describe(k *kind, params map[string]string, match func(resource) bool) (map[string]interface{}, *apiError) {
+	pageNumber, apiErr := intParam(params, "PageNumber", 1, 1, 1<<30)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	pageSize, apiErr := intParam(params, "PageSize", 10, 1, k.maxPageSize)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	matched := []resource{}
+	for _, item := range st.resources[k] {
+		if item["RegionId"] == params["RegionId"] && (match == nil || match(item)) {
+			matched = append(matched, item)
+		}
+	}
+	start := min((pageNumber-1)*pageSize, len(matched))
+	end := min(start+pageSize, len(matched))
+	return map[string]interface{}{
+		"TotalCount": len(matched),
+		"PageNumber": pageNumber,
+		"PageSize":   pageSize,
+		k.listKey:    map[string]interface{}{k.itemKey: matched[start:end]},
+	}, nil
+}
+
+// intParam 读取整数参数，未传入时为 fallback
+func intParam(params map[string]string, name string, fallback, minValue, maxValue int) (int, *apiError) {
+	value, ok := params[name]
+	if !ok || value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < minValue || n > maxValue {
+		return 0, invalidParameter(name)
+	}
+	return n, nil
+}
+
+// listParam 读取 JSON 数组格式的参数（例如 InstanceIds、DiskIds），未传入时返回 nil
+func listParam(params map[string]string, name string) ([]string, *apiError) {
+	value := params[name]
+	if value == "" {
+		return nil, nil
+	}
+	var list []string
+	if err := json.Unmarshal([]byte(value), &list); err != nil {
+		return nil, invalidParameter(name)
+	}
+	return list, nil
+}
+
+// field 按点分隔的路径读取资源的字段
+func field(item resource, path string) interface{} {
+	var value interface{} = item
+	for _, key := range strings.Split(path, ".") {
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = fields[key]
+	}
+	return value
+}
+
+// stringField 读取字符串字段，不存在时为空
+func stringField(item resource, path string) string {
+	value, _ := field(item, path).(string)
+	return value
+}
+
+// hasAny 字段（字符串或字符串数组）是否包含 values 中的任意一个
+func hasAny(item resource, path string, values []string) bool {
+	var actual []string
+	switch value := field(item, path).(type) {
+	case string:
+		actual = []string{value}
+	case []interface{}:
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				actual = append(actual, s)
+			}
+		}
+	}
+	for _, want := range values {
+		for _, have := range actual {
+			if want == have {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchName 名称筛选，支持 * 通配符（与控制台的模糊搜索相同）
+func matchName(pattern, name string) bool {
+	if !strings.Contains(pattern, "*") {
+		return pattern == name
+	}
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(name, parts[0]) {
+		return false
+	}
+	name = name[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(name, part)
+		if i < 0 {
+			return false
+		}
+		name = name[i+len(part):]
+	}
+	return strings.HasSuffix(name, parts[len(parts)-1])
+}
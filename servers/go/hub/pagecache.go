@@ -0,0 +1,158 @@
+package hub
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 渲染缓存最多保存的页面数，超过后淘汰最久没有使用的页面
+const maxRenderCacheEntries = 1024
+
+// renderKey 渲染缓存的键
+// origin 为 pageOrigin 的结果：映射到站点的域名为 协议://Host，其他 Host 为空，客户端无法用任意 Host 制造新的键
+type renderKey struct {
+	site     string
+	page     string
+	basePath string
+	origin   string
+}
+
+// renderedPage 渲染好的页面及其压缩版本
+type renderedPage struct {
+	html    []byte
+	encoded map[string][]byte // 编码名 → 压缩后的内容，内容太小不值得压缩时为 nil
+	etag    string
+}
+
+// renderCache 已渲染页面的内存缓存（LRU）
+// 页面只取决于配置、模板和 base_path，缓存挂在站点状态快照上，
+// 配置或模板（包括 include/extends 链上的任意文件）变化触发重载时随旧快照一起失效
+type renderCache struct {
+	mu      sync.Mutex
+	entries map[renderKey]*list.Element
+	order   *list.List // 最近使用的在前，元素为 *renderCacheEntry
+}
+
+type renderCacheEntry struct {
+	key  renderKey
+	page *renderedPage
+}
+
+func newRenderCache() *renderCache {
+	return &renderCache{entries: make(map[renderKey]*list.Element), order: list.New()}
+}
+
+func (c *renderCache) get(key renderKey) *renderedPage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*renderCacheEntry).page
+}
+
+func (c *renderCache) put(key renderKey, page *renderedPage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*renderCacheEntry).page = page
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&renderCacheEntry{key: key, page: page})
+	for c.order.Len() > maxRenderCacheEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*renderCacheEntry).key)
+	}
+}
+
+// pageOrigin 模板中 base_url / root_url 使用的 协议://Host
+// 只有映射到站点的域名使用请求的 Host；其他 Host 由客户端任意填写，返回空字符串，base_url / root_url 为不带域名的路径
+func pageOrigin(state *siteState, origin string) string {
+	_, host, _ := strings.Cut(origin, "://")
+	if _, mapped := state.domainToSite[hostname(host)]; mapped {
+		return origin
+	}
+	return ""
+}
+
+// newRenderedPage 计算强 ETag 并预先压缩
+func newRenderedPage(html []byte) *renderedPage {
+	sum := sha256.Sum256(html)
+	page := &renderedPage{
+		html: html,
+		etag: `"` + hex.EncodeToString(sum[:16]) + `"`,
+	}
+
+	// 如果内容小于 1KB 不压缩
+	if len(html) >= 1024 {
+		page.encoded = make(map[string][]byte, len(contentEncodings))
+		for _, enc := range contentEncodings {
+			if data, err := compressBytes(enc.name, html); err == nil {
+				page.encoded[enc.name] = data
+			}
+		}
+	}
+	return page
+}
+
+// etagMatch 判断 If-None-Match 是否命中（弱比较，忽略 W/ 前缀）
+func etagMatch(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// sendRenderedPage 发送已渲染的页面，支持 If-None-Match 返回 304
+func (s *Server) sendRenderedPage(w http.ResponseWriter, r *http.Request, page *renderedPage) {
+	var offered []string
+	for _, enc := range contentEncodings {
+		if page.encoded[enc.name] != nil {
+			offered = append(offered, enc.name)
+		}
+	}
+	encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"), offered)
+
+	// 不同编码是不同的表示，使用不同的 ETag
+	etag := page.etag
+	if encoding != "" {
+		etag = strings.TrimSuffix(etag, `"`) + "-" + encoding + `"`
+	}
+
+	header := w.Header()
+	header.Set("ETag", etag)
+	if header.Get("Cache-Control") == "" {
+		header.Set("Cache-Control", "no-cache")
+	}
+	header.Set("Vary", "Accept-Encoding")
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" && etagMatch(inm, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	body := page.html
+	if encoding != "" {
+		body = page.encoded[encoding]
+		header.Set("Content-Encoding", encoding)
+	}
+
+	header.Set("Content-Type", "text/html; charset=utf-8")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		w.Write(body)
+	}
+}
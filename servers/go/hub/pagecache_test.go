@@ -0,0 +1,48 @@
+package hub
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRenderCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newRenderCache()
+	page := newRenderedPage([]byte("page"))
+	first := renderKey{site: "aliyun", page: "index"}
+	c.put(first, page)
+	for i := 1; i < maxRenderCacheEntries; i++ {
+		c.put(renderKey{site: "aliyun", page: fmt.Sprint(i)}, page)
+	}
+	// 访问过的页面不会被淘汰，缓存已满时新页面替换最久没有使用的页面
+	if c.get(first) == nil {
+		t.Fatal("first page missing before cache is full")
+	}
+	latest := renderKey{site: "aliyun", page: "latest"}
+	c.put(latest, page)
+	if c.get(latest) == nil {
+		t.Fatal("new page not cached when cache is full")
+	}
+	if c.get(first) == nil {
+		t.Fatal("recently used page evicted")
+	}
+	if c.get(renderKey{site: "aliyun", page: "1"}) != nil {
+		t.Fatal("least recently used page not evicted")
+	}
+	if len(c.entries) != maxRenderCacheEntries || c.order.Len() != maxRenderCacheEntries {
+		t.Fatalf("cache size = %d/%d, want %d", len(c.entries), c.order.Len(), maxRenderCacheEntries)
+	}
+}
+
+func TestPageOrigin(t *testing.T) {
+	state := &siteState{domainToSite: map[string]string{"aliyun.example.com": "aliyun"}}
+	for origin, want := range map[string]string{
+		"https://aliyun.example.com":      "https://aliyun.example.com",
+		"https://aliyun.example.com:8443": "https://aliyun.example.com:8443",
+		"http://localhost:8080":           "",
+		"http://attacker.example":         "",
+	} {
+		if got := pageOrigin(state, origin); got != want {
+			t.Errorf("pageOrigin(%q) = %q, want %q", origin, got, want)
+		}
+	}
+}
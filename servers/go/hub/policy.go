@@ -0,0 +1,173 @@
+package hub
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 策略规则的效果
+const (
+	PolicyAllow   = "allow"   // 放行；配置了 confirmMessage 的操作仍需确认
+	PolicyDeny    = "deny"    // 拒绝
+	PolicyConfirm = "confirm" // 放行，但无论是否配置了 confirmMessage 都需要确认令牌
+)
+
+// PolicyRule 一条策略规则，Site、User、Credential、Resource、Action、API 为空时匹配所有，支持 path.Match 的通配符
+type PolicyRule struct {
+	Site       string // 站点名
+	User       string // API 用户名（APIUser.Name）
+	Credential string // 凭证名称（Credential.Name）
+	Resource   string // 站点配置 resource_manage 中的资源，例如 eip；通过签名代理直接调用的接口没有资源
+	Action     string // 资源的操作名（actions[].name），例如 release；通过签名代理直接调用的接口没有操作名
+	API        string // 调用的云服务 API，格式为 {product}/{action}，例如 vpc/ReleaseEipAddress 或 ecs/Delete*
+	Effect     string // allow、deny 或 confirm
+}
+
+// policyRequest 需要按策略判断的一次操作
+type policyRequest struct {
+	Site, User, Credential, Resource, Action, API string
+}
+
+// matches 规则是否匹配该操作
+func (r PolicyRule) matches(req policyRequest) bool {
+	return policyMatch(r.Site, req.Site) && policyMatch(r.User, req.User) && policyMatch(r.Credential, req.Credential) &&
+		policyMatch(r.Resource, req.Resource) && policyMatch(r.Action, req.Action) && policyMatch(r.API, req.API)
+}
+
+// policyMatch 空模式匹配所有
+func policyMatch(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	matched, _ := path.Match(pattern, value)
+	return matched
+}
+
+// Policy 资源管理操作（/{site}/api/manage/{resource}/{action}）和签名代理中非只读接口的服务端策略
+// 规则按顺序匹配，第一条匹配的规则决定效果，没有规则匹配时使用 Default
+type Policy struct {
+	Rules      []PolicyRule
+	Default    string        // 没有规则匹配时的效果，为空表示 deny
+	ConfirmTTL time.Duration // 确认令牌的有效期，0 表示 2 分钟
+}
+
+// Validate 检查配置是否可用
+func (p Policy) Validate() error {
+	if !validPolicyEffect(p.Default, true) {
+		return fmt.Errorf("default must be allow, deny or confirm")
+	}
+	if p.ConfirmTTL < 0 {
+		return fmt.Errorf("confirm_ttl must not be negative")
+	}
+	for i, rule := range p.Rules {
+		if !validPolicyEffect(rule.Effect, false) {
+			return fmt.Errorf("rules[%d]: effect must be allow, deny or confirm", i)
+		}
+		for _, pattern := range []string{rule.Site, rule.User, rule.Credential, rule.Resource, rule.Action, rule.API} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("rules[%d]: invalid pattern %q", i, pattern)
+			}
+		}
+	}
+	return nil
+}
+
+func validPolicyEffect(effect string, allowEmpty bool) bool {
+	switch effect {
+	case PolicyAllow, PolicyDeny, PolicyConfirm:
+		return true
+	case "":
+		return allowEmpty
+	}
+	return false
+}
+
+// withDefaults 填充未配置的字段
+func (p Policy) withDefaults() Policy {
+	if p.Default == "" {
+		p.Default = PolicyDeny
+	}
+	if p.ConfirmTTL == 0 {
+		p.ConfirmTTL = 2 * time.Minute
+	}
+	return p
+}
+
+// decide 返回操作适用的效果
+func (p Policy) decide(req policyRequest) string {
+	for _, rule := range p.Rules {
+		if rule.matches(req) {
+			return rule.Effect
+		}
+	}
+	return p.Default
+}
+
+// confirmTokens 签发和校验确认令牌
+// 令牌绑定到具体的站点、用户、凭证、操作、地域和资源，只能使用一次；签名密钥在启动时随机生成，重启后之前的令牌失效
+type confirmTokens struct {
+	key [32]byte
+	ttl time.Duration
+
+	mu   sync.Mutex
+	used map[string]time.Time // 有效期内已使用的令牌
+}
+
+func newConfirmTokens(ttl time.Duration) *confirmTokens {
+	c := &confirmTokens{ttl: ttl, used: make(map[string]time.Time)}
+	rand.Read(c.key[:])
+	return c
+}
+
+// issue 签发令牌：base64(过期时间 + 随机数).base64(HMAC-SHA256)
+func (c *confirmTokens) issue(subject string) string {
+	payload := make([]byte, 16)
+	binary.BigEndian.PutUint64(payload, uint64(time.Now().Add(c.ttl).Unix()))
+	rand.Read(payload[8:])
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + c.sign(encoded, subject)
+}
+
+// verify 校验令牌并标记为已使用
+func (c *confirmTokens) verify(token, subject string) bool {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(c.sign(encoded, subject))) {
+		return false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(payload) != 16 {
+		return false
+	}
+	now := time.Now()
+	expires := time.Unix(int64(binary.BigEndian.Uint64(payload)), 0)
+	if now.After(expires) {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for used, until := range c.used {
+		if now.After(until) {
+			delete(c.used, used)
+		}
+	}
+	if _, used := c.used[encoded]; used {
+		return false
+	}
+	c.used[encoded] = expires
+	return true
+}
+
+func (c *confirmTokens) sign(encoded, subject string) string {
+	mac := hmac.New(sha256.New, c.key[:])
+	mac.Write([]byte(encoded + "\n" + subject))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
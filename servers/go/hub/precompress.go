@@ -0,0 +1,147 @@
+package hub
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 小于该大小的文件不生成预压缩文件
+const minPrecompressSize = 1024
+
+// Precompress 为站点静态文件（{sitesDir}/*/static）和 CDN 缓存生成 .br/.zst/.gz 预压缩文件
+// 只处理可压缩的文本类型，已是最新的预压缩文件会跳过，可以在启动时或构建前重复执行
+func (s *Server) Precompress(sitesDir string) error {
+	var dirs []string
+	if sitesDir != "" {
+		staticDirs, err := filepath.Glob(filepath.Join(sitesDir, "*", "static"))
+		if err != nil {
+			return err
+		}
+		dirs = append(dirs, staticDirs...)
+	}
+	if s.cdnCacheDir != "" {
+		dirs = append(dirs, s.cdnCacheDir)
+	}
+
+	total := 0
+	for _, dir := range dirs {
+		count, err := PrecompressDir(dir)
+		if err != nil {
+			return err
+		}
+		total += count
+	}
+	s.logger.Printf("Precompressed %d files", total)
+	return nil
+}
+
+// PrecompressDir 为 dir 下所有可压缩的文件生成预压缩文件，返回处理的文件数
+func PrecompressDir(dir string) (int, error) {
+	count := 0
+	err := filepath.WalkDir(dir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && filePath == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || isSidecar(filePath) || !isCompressible(contentTypeByExtension(filePath)) {
+			return nil
+		}
+		if err := precompressFile(filePath); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// isSidecar 判断是否为预压缩文件本身
+func isSidecar(filePath string) bool {
+	for _, enc := range contentEncodings {
+		if strings.HasSuffix(filePath, enc.ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// precompressFile 为单个文件生成各编码的预压缩文件
+// 预压缩文件的修改时间与原文件相同，原文件之后被修改时会被识别为过期；
+// 压缩后没有变小的编码不生成（并删除旧的预压缩文件）
+func precompressFile(filePath string) error {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+	if info.Size() < minPrecompressSize || !isCompressible(contentTypeByExtension(filePath)) {
+		return nil
+	}
+
+	var data []byte
+	for _, enc := range contentEncodings {
+		sidecarPath := filePath + enc.ext
+		if sidecarInfo, err := os.Stat(sidecarPath); err == nil && sidecarInfo.ModTime().Equal(info.ModTime()) {
+			continue
+		}
+
+		if data == nil {
+			if data, err = os.ReadFile(filePath); err != nil {
+				return err
+			}
+		}
+
+		var buf bytes.Buffer
+		c := enc.best()
+		c.Reset(&buf)
+		if _, err := c.Write(data); err != nil {
+			return err
+		}
+		if err := c.Close(); err != nil {
+			return err
+		}
+		if buf.Len() >= len(data) {
+			os.Remove(sidecarPath)
+			continue
+		}
+
+		if err := writeFileAtomic(sidecarPath, buf.Bytes()); err != nil {
+			return err
+		}
+		if err := os.Chtimes(sidecarPath, info.ModTime(), info.ModTime()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFileAtomic 先写临时文件再重命名，正在处理的请求不会读到写了一半的文件
+func writeFileAtomic(filePath string, data []byte) error {
+	return writeFileAtomicPerm(filePath, data, 0644)
+}
+
+// writeFileAtomicPerm 与 writeFileAtomic 相同，使用指定的文件权限
+func writeFileAtomicPerm(filePath string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
+}
@@ -0,0 +1,261 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	proxyproto "github.com/pires/go-proxyproto"
+)
+
+// TrustedProxies 受信任的反向代理地址段
+// 只有直接连接来自这些地址时，才会采信 Forwarded、X-Forwarded-*、X-Real-IP 请求头
+type TrustedProxies []netip.Prefix
+
+// ParseTrustedProxies 解析 CIDR 或单个 IP 列表，例如 "127.0.0.1"、"10.0.0.0/8"、"::1"
+func ParseTrustedProxies(list []string) (TrustedProxies, error) {
+	var proxies TrustedProxies
+	for _, item := range list {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if strings.Contains(item, "/") {
+			prefix, err := netip.ParsePrefix(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", item, err)
+			}
+			proxies = append(proxies, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", item, err)
+		}
+		addr = addr.Unmap()
+		proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return proxies, nil
+}
+
+// Contains 判断地址是否属于受信任的代理
+func (t TrustedProxies) Contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range t {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewProxyProtocolListener 包装 listener 以接受 PROXY protocol v1/v2
+// 来自受信任代理的连接使用 PROXY 头中的客户端地址，其他连接发送 PROXY 头时会被拒绝
+func NewProxyProtocolListener(ln net.Listener, trusted TrustedProxies) net.Listener {
+	return &proxyproto.Listener{
+		Listener: ln,
+		Policy: func(upstream net.Addr) (proxyproto.Policy, error) {
+			if addr, ok := addrFromNetAddr(upstream); ok && trusted.Contains(addr) {
+				return proxyproto.USE, nil
+			}
+			return proxyproto.REJECT, nil
+		},
+	}
+}
+
+// addrFromNetAddr 取出 net.Addr 中的 IP
+func addrFromNetAddr(addr net.Addr) (netip.Addr, bool) {
+	if tcpAddr, ok := addr.(*net.TCPAddr); ok {
+		ip, ok := netip.AddrFromSlice(tcpAddr.IP)
+		return ip.Unmap(), ok
+	}
+	return parseNodeAddr(addr.String())
+}
+
+// requestInfo 经过可信代理解析后的请求来源
+type requestInfo struct {
+	clientIP string
+	scheme   string // http 或 https
+	host     string // 客户端请求的 Host（可能带端口）
+}
+
+type requestInfoKey struct{}
+
+// ClientIP 返回请求的真实客户端 IP（经过可信代理解析）
+// 用于 Server 处理的请求；其他请求返回 RemoteAddr 中的 IP
+func ClientIP(r *http.Request) string {
+	if info, ok := r.Context().Value(requestInfoKey{}).(requestInfo); ok {
+		return info.clientIP
+	}
+	if addr, ok := parseNodeAddr(r.RemoteAddr); ok {
+		return addr.String()
+	}
+	return r.RemoteAddr
+}
+
+// withRequestInfo 解析请求来源并保存到请求上下文
+func (s *Server) withRequestInfo(r *http.Request) (*http.Request, requestInfo) {
+	info := s.resolveRequest(r)
+	return r.WithContext(context.WithValue(r.Context(), requestInfoKey{}, info)), info
+}
+
+// resolveRequest 确定客户端 IP、协议和 Host
+// 直接连接来自受信任代理时，依次采信 RFC 7239 Forwarded、X-Forwarded-For、X-Real-IP；
+// 转发链从右往左跳过受信任的代理，第一个不受信任的地址即客户端
+func (s *Server) resolveRequest(r *http.Request) requestInfo {
+	info := requestInfo{scheme: "http", host: r.Host}
+	if r.TLS != nil {
+		info.scheme = "https"
+	}
+
+	remote, ok := parseNodeAddr(r.RemoteAddr)
+	if !ok {
+		info.clientIP = r.RemoteAddr
+		return info
+	}
+	info.clientIP = remote.String()
+	if !s.trustedProxies.Contains(remote) {
+		return info
+	}
+
+	// RFC 7239 Forwarded
+	if forwarded := r.Header.Values("Forwarded"); len(forwarded) > 0 {
+		elements := parseForwarded(strings.Join(forwarded, ","))
+		chain := make([]string, len(elements))
+		for i, element := range elements {
+			chain[i] = element["for"]
+		}
+		if i, client, ok := s.clientFromChain(chain); ok {
+			info.clientIP = client.String()
+			if proto := strings.ToLower(elements[i]["proto"]); proto == "http" || proto == "https" {
+				info.scheme = proto
+			}
+			if host := elements[i]["host"]; validHost(host) {
+				info.host = host
+			}
+		}
+		return info
+	}
+
+	// X-Forwarded-For / X-Real-IP
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		if _, client, ok := s.clientFromChain(strings.Split(strings.Join(xff, ","), ",")); ok {
+			info.clientIP = client.String()
+		}
+	} else if realIP, ok := parseNodeAddr(r.Header.Get("X-Real-IP")); ok {
+		info.clientIP = realIP.String()
+	}
+
+	// 多级代理时第一个值是客户端的原始请求
+	if proto := strings.ToLower(firstValue(r.Header.Get("X-Forwarded-Proto"))); proto == "http" || proto == "https" {
+		info.scheme = proto
+	}
+	if host := firstValue(r.Header.Get("X-Forwarded-Host")); validHost(host) {
+		info.host = host
+	}
+	return info
+}
+
+// clientFromChain 从转发链中找出客户端：从右往左跳过受信任的代理
+// 遇到无法解析的地址时停止，使用最后一个已确认的地址；整条链都受信任时使用最左边的地址
+func (s *Server) clientFromChain(chain []string) (int, netip.Addr, bool) {
+	index, client, found := -1, netip.Addr{}, false
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr, ok := parseNodeAddr(chain[i])
+		if !ok {
+			break
+		}
+		index, client, found = i, addr, true
+		if !s.trustedProxies.Contains(addr) {
+			break
+		}
+	}
+	return index, client, found
+}
+
+// parseNodeAddr 解析 "1.2.3.4"、"1.2.3.4:80"、"[2001:db8::1]:80"、"2001:db8::1" 形式的地址
+func parseNodeAddr(node string) (netip.Addr, bool) {
+	node = strings.Trim(strings.TrimSpace(node), `"`)
+	if node == "" {
+		return netip.Addr{}, false
+	}
+	if addrPort, err := netip.ParseAddrPort(node); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(node, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// parseForwarded 解析 Forwarded 请求头，每个元素是 for/proto/host/by 参数表（参数名小写）
+func parseForwarded(value string) []map[string]string {
+	var elements []map[string]string
+	for _, element := range splitQuoted(value, ',') {
+		params := make(map[string]string)
+		for _, pair := range splitQuoted(element, ';') {
+			key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok {
+				continue
+			}
+			val = strings.TrimSpace(val)
+			if len(val) >= 2 && val[0] == '"' && val[len(val)-1] == '"' {
+				val = strings.ReplaceAll(val[1:len(val)-1], `\"`, `"`)
+			}
+			params[strings.ToLower(strings.TrimSpace(key))] = val
+		}
+		elements = append(elements, params)
+	}
+	return elements
+}
+
+// splitQuoted 按 sep 分割，忽略引号内的分隔符
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	inQuotes, escaped, start := false, false, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case escaped:
+			escaped = false
+		case c == '\\' && inQuotes:
+			escaped = true
+		case c == '"':
+			inQuotes = !inQuotes
+		case c == sep && !inQuotes:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// firstValue 取逗号分隔列表中的第一个值
+func firstValue(value string) string {
+	first, _, _ := strings.Cut(value, ",")
+	return strings.TrimSpace(first)
+}
+
+// validHost 检查转发来的 Host 是否是合法的 host[:port]
+func validHost(host string) bool {
+	if host == "" || len(host) > 255 {
+		return false
+	}
+	for _, c := range host {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune(".-:[]_", c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// hostname 去掉 Host 中的端口号（支持 IPv6 字面量）
+func hostname(hostport string) string {
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		return host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(hostport, "["), "]")
+}
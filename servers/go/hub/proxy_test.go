@@ -0,0 +1,148 @@
+package hub
+
+import (
+	"io"
+	"net"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestResolveRequest(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", "::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &Server{trustedProxies: trusted}
+
+	tests := []struct {
+		name   string
+		remote string
+		header map[string]string
+		client string
+		scheme string
+		host   string
+	}{
+		{"direct", "203.0.113.5:1234", nil, "203.0.113.5", "http", "example.com"},
+		{"untrusted peer ignores headers", "203.0.113.5:1234",
+			map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.2", "Forwarded": "for=198.51.100.3", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.example"},
+			"203.0.113.5", "http", "example.com"},
+		{"trusted peer uses X-Forwarded-For", "10.0.0.1:1234",
+			map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "hub.example"},
+			"198.51.100.1", "https", "hub.example"},
+		{"walk skips trusted hops", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "198.51.100.1, 10.0.0.3, 10.0.0.2"}, "198.51.100.1", "http", "example.com"},
+		{"walk stops at first untrusted hop", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "192.0.2.66, 198.51.100.1, 10.0.0.2"}, "198.51.100.1", "http", "example.com"},
+		{"unparsable hop keeps last confirmed address", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "198.51.100.1, garbage, 10.0.0.2"}, "10.0.0.2", "http", "example.com"},
+		{"all hops trusted uses leftmost", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"}, "10.0.0.3", "http", "example.com"},
+		{"Forwarded quoted IPv6 with port", "10.0.0.1:1234",
+			map[string]string{"Forwarded": `for="[2001:db8::1]:1234";proto=https;host=hub.example`},
+			"2001:db8::1", "https", "hub.example"},
+		{"Forwarded takes precedence", "10.0.0.1:1234",
+			map[string]string{"Forwarded": "for=198.51.100.3", "X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.2"},
+			"198.51.100.3", "http", "example.com"},
+		{"Forwarded chain uses element of the client", "10.0.0.1:1234",
+			map[string]string{"Forwarded": `for=198.51.100.3;proto=https, for="10.0.0.2";proto=http`},
+			"198.51.100.3", "https", "example.com"},
+		{"Forwarded IPv4 with port", "10.0.0.1:1234", map[string]string{"Forwarded": `for="198.51.100.3:4711"`}, "198.51.100.3", "http", "example.com"},
+		{"X-Forwarded-For over X-Real-IP", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.2"}, "198.51.100.1", "http", "example.com"},
+		{"X-Real-IP without X-Forwarded-For", "10.0.0.1:1234", map[string]string{"X-Real-IP": "198.51.100.2"}, "198.51.100.2", "http", "example.com"},
+		{"trusted IPv6 loopback peer", "[::1]:1234", map[string]string{"X-Real-IP": "2001:db8::2"}, "2001:db8::2", "http", "example.com"},
+		{"invalid forwarded host ignored", "10.0.0.1:1234", map[string]string{"X-Forwarded-Host": "bad host/<x>"}, "10.0.0.1", "http", "example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "http://example.com/", nil)
+			req.RemoteAddr = tt.remote
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			info := srv.resolveRequest(req)
+			if info.clientIP != tt.client || info.scheme != tt.scheme || info.host != tt.host {
+				t.Errorf("resolveRequest = %+v, want {%s %s %s}", info, tt.client, tt.scheme, tt.host)
+			}
+		})
+	}
+
+	// 多个 X-Forwarded-For 请求头按顺序拼接成一条链
+	req := httptest.NewRequest("GET", "http://example.com/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Add("X-Forwarded-For", "192.0.2.66, 198.51.100.1")
+	req.Header.Add("X-Forwarded-For", "10.0.0.2")
+	if info := srv.resolveRequest(req); info.clientIP != "198.51.100.1" {
+		t.Errorf("multiple X-Forwarded-For headers: client %s, want 198.51.100.1", info.clientIP)
+	}
+}
+
+func TestParseNodeAddr(t *testing.T) {
+	tests := map[string]string{
+		"192.0.2.1":            "192.0.2.1",
+		"192.0.2.1:80":         "192.0.2.1",
+		`"[2001:db8::1]:1234"`: "2001:db8::1",
+		"[2001:db8::1]":        "2001:db8::1",
+		"2001:db8::1":          "2001:db8::1",
+		"::ffff:192.0.2.1":     "192.0.2.1",
+		"unknown":              "",
+		"_hidden":              "",
+		"":                     "",
+	}
+	for node, want := range tests {
+		addr, ok := parseNodeAddr(node)
+		if got := ""; ok {
+			got = addr.String()
+			if got != want {
+				t.Errorf("parseNodeAddr(%q) = %s, want %s", node, got, want)
+			}
+		} else if want != "" {
+			t.Errorf("parseNodeAddr(%q) failed, want %s", node, want)
+		}
+	}
+}
+
+// proxyProtocolRemote 通过 PROXY protocol listener 建立一个连接，发送 PROXY 头后返回服务端看到的地址和读取结果
+func proxyProtocolRemote(t *testing.T, trusted TrustedProxies) (net.Addr, error) {
+	t.Helper()
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln := NewProxyProtocolListener(inner, trusted)
+	defer ln.Close()
+
+	go func() {
+		conn, err := net.Dial("tcp", inner.Addr().String())
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.WriteString(conn, "PROXY TCP4 203.0.113.5 127.0.0.1 4711 80\r\nGET / HTTP/1.0\r\n\r\n")
+		io.Copy(io.Discard, conn)
+	}()
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	buf := make([]byte, 3)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, err
+	}
+	if string(buf) != "GET" {
+		t.Fatalf("read %q after PROXY header", buf)
+	}
+	return conn.RemoteAddr(), nil
+}
+
+func TestProxyProtocolListener(t *testing.T) {
+	remote, err := proxyProtocolRemote(t, TrustedProxies{netip.MustParsePrefix("127.0.0.1/32")})
+	if err != nil {
+		t.Fatalf("trusted peer: %v", err)
+	}
+	if addr, ok := addrFromNetAddr(remote); !ok || addr.String() != "203.0.113.5" {
+		t.Fatalf("trusted peer remote = %v", remote)
+	}
+
+	if _, err := proxyProtocolRemote(t, TrustedProxies{netip.MustParsePrefix("10.0.0.0/8")}); err == nil {
+		t.Fatal("untrusted peer: PROXY header accepted")
+	}
+}
@@ -0,0 +1,346 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// 路由类别，用于按类别配置速率限制
+const (
+	RoutePage   = "page"   // 平台首页和站点页面
+	RouteStatic = "static" // 站点静态文件
+	RouteCDN    = "cdn"    // /cdn/ 代理
+	RouteAPI    = "api"    // 站点 /api/ 接口
+	RouteAdmin  = "admin"  // /_admin/ 管理接口
+	RouteHealth = "health" // /_health 健康检查
+)
+
+// 流量超出预算时的处理方式
+const (
+	TrafficReject = "reject" // 返回 429
+	TrafficShape  = "shape"  // 继续响应，但限速到 ShapeRate
+)
+
+// routeClasses 所有合法的路由类别
+var routeClasses = []string{RoutePage, RouteStatic, RouteCDN, RouteAPI, RouteAdmin, RouteHealth}
+
+// RateLimitKey 一次请求在速率限制中的归属
+type RateLimitKey struct {
+	IP    string
+	Site  string // 请求所属的站点，平台首页、CDN 和管理接口为空
+	Route string // 路由类别，RoutePage 等
+}
+
+// RateLimitResult 速率限制检查结果
+type RateLimitResult struct {
+	Allowed bool
+	Reason  string // 被拒绝时的原因，作为 429 的响应内容
+
+	// ThrottleRate 大于 0 时响应需要限速到该速率（字节/秒），流量超出预算且处于整形模式时设置
+	ThrottleRate int64
+
+	// 以下字段用于 RateLimit-* 响应头，Limit 为 0 表示不限制请求数
+	Limit      int           // 每个窗口允许的请求数
+	Window     time.Duration // 窗口长度
+	Remaining  int           // 剩余可用的请求数
+	Reset      time.Duration // 额度完全恢复所需的时间
+	RetryAfter time.Duration // 被拒绝时建议的等待时间
+}
+
+// Limiter 按客户端进行请求数和流量限制
+type Limiter interface {
+	// Allow 记录一次请求，超过请求数限制或流量已用完时返回 Allowed=false；
+	// 流量整形时返回 Allowed=true 并设置 ThrottleRate
+	Allow(key RateLimitKey) RateLimitResult
+	// RecordTraffic 记录一次响应实际写出的字节数（压缩之后）
+	RecordTraffic(key RateLimitKey, bytes int64)
+}
+
+// RateLimitRule 一组限制
+// 请求数使用令牌桶：每个窗口补充 MaxRequests 个令牌，桶容量为 Burst；
+// 流量使用滑动窗口计数：按上一个窗口的剩余比例加上当前窗口的字节数估算
+type RateLimitRule struct {
+	Window      time.Duration // 为 0 时使用默认规则的窗口
+	MaxRequests int           // 每个窗口的请求数，0 表示不限制
+	Burst       int           // 令牌桶容量，0 表示等于 MaxRequests
+	MaxBytes    int64         // 每个窗口的字节数，0 表示不限制
+}
+
+// SiteRateLimitConfig 单个站点的速率限制
+type SiteRateLimitConfig struct {
+	RateLimitRule
+	Routes map[string]RateLimitRule // 按路由类别覆盖
+}
+
+// RateLimitConfig 速率限制配置
+// 规则按 站点+路由类别 > 站点 > 路由类别 > 默认 的顺序选择最具体的一条，
+// 使用同一条规则的请求共享同一个计数
+type RateLimitConfig struct {
+	RateLimitRule                                // 默认规则
+	Routes        map[string]RateLimitRule       // 按路由类别覆盖
+	Sites         map[string]SiteRateLimitConfig // 按站点覆盖
+	MaxEntries    int                            // 最多跟踪的计数数量，超过后淘汰最久未使用的，0 表示 10000
+
+	// TrafficMode 流量超出 MaxBytes 后的处理方式：TrafficReject（默认）或 TrafficShape
+	TrafficMode string
+	// ShapeRate 整形模式下超出预算的客户端的限速（字节/秒），同一 IP 的所有响应共享
+	ShapeRate int64
+}
+
+// DefaultRateLimitConfig 默认速率限制
+var DefaultRateLimitConfig = RateLimitConfig{
+	RateLimitRule: RateLimitRule{
+		Window:      time.Minute,
+		MaxRequests: 1000,              // 每分钟1000个请求（静态资源服务器）
+		MaxBytes:    100 * 1024 * 1024, // 每分钟100MB
+	},
+}
+
+// Validate 检查配置是否可用
+func (c RateLimitConfig) Validate() error {
+	if err := c.RateLimitRule.validate(); err != nil {
+		return err
+	}
+	if c.Window <= 0 {
+		return fmt.Errorf("window must be positive")
+	}
+	switch c.TrafficMode {
+	case "", TrafficReject:
+	case TrafficShape:
+		if c.ShapeRate <= 0 {
+			return fmt.Errorf("shape_rate must be positive when traffic_mode is %q", TrafficShape)
+		}
+	default:
+		return fmt.Errorf("unknown traffic_mode %q (expected %q or %q)", c.TrafficMode, TrafficReject, TrafficShape)
+	}
+	if err := validateRoutes(c.Routes); err != nil {
+		return err
+	}
+	for site, siteConfig := range c.Sites {
+		if err := siteConfig.RateLimitRule.validate(); err != nil {
+			return fmt.Errorf("sites.%s: %w", site, err)
+		}
+		if err := validateRoutes(siteConfig.Routes); err != nil {
+			return fmt.Errorf("sites.%s: %w", site, err)
+		}
+	}
+	return nil
+}
+
+func (r RateLimitRule) validate() error {
+	if r.Window < 0 || r.MaxRequests < 0 || r.Burst < 0 || r.MaxBytes < 0 {
+		return fmt.Errorf("window, max_requests, burst and max_bytes must not be negative")
+	}
+	return nil
+}
+
+func validateRoutes(routes map[string]RateLimitRule) error {
+	for route, rule := range routes {
+		if !isRouteClass(route) {
+			return fmt.Errorf("routes: unknown route class %q (expected one of %v)", route, routeClasses)
+		}
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("routes.%s: %w", route, err)
+		}
+	}
+	return nil
+}
+
+func isRouteClass(route string) bool {
+	for _, class := range routeClasses {
+		if class == route {
+			return true
+		}
+	}
+	return false
+}
+
+// rule 选择适用于 key 的规则，返回规则名（作为计数的一部分）和规则
+func (c *RateLimitConfig) rule(key RateLimitKey) (string, RateLimitRule) {
+	name, rule := "default", c.RateLimitRule
+	if siteConfig, ok := c.Sites[key.Site]; ok && key.Site != "" {
+		if routeRule, ok := siteConfig.Routes[key.Route]; ok {
+			name, rule = "site:"+key.Site+"/"+key.Route, routeRule
+		} else {
+			name, rule = "site:"+key.Site, siteConfig.RateLimitRule
+		}
+	} else if routeRule, ok := c.Routes[key.Route]; ok {
+		name, rule = "route:"+key.Route, routeRule
+	}
+	if rule.Window <= 0 {
+		rule.Window = c.Window
+	}
+	if rule.Burst <= 0 {
+		rule.Burst = rule.MaxRequests
+	}
+	return name, rule
+}
+
+// rateLimitStoreTimeout 单次访问计数存储的超时，超时后放行请求
+const rateLimitStoreTimeout = 500 * time.Millisecond
+
+// RateLimiter 速率限制器，计数保存在 RateLimitStore 中
+// 使用共享存储（例如 Redis）时，多个实例合计使用同一份额度
+type RateLimiter struct {
+	config RateLimitConfig
+	store  RateLimitStore
+	logger *log.Logger
+	now    func() time.Time
+
+	lastStoreError atomic.Int64 // 上次记录存储错误日志的时间（UnixNano），避免存储故障时刷屏
+}
+
+// NewRateLimiter 创建使用内存存储的速率限制器，logger 为 nil 时使用 log.Default()
+func NewRateLimiter(config RateLimitConfig, logger *log.Logger) *RateLimiter {
+	return NewRateLimiterWithStore(config, NewMemoryStore(config.MaxEntries), logger)
+}
+
+// NewRateLimiterWithStore 创建使用指定存储的速率限制器，logger 为 nil 时使用 log.Default()
+func NewRateLimiterWithStore(config RateLimitConfig, store RateLimitStore, logger *log.Logger) *RateLimiter {
+	if logger == nil {
+		logger = log.Default()
+	}
+	if config.Window <= 0 {
+		config.Window = DefaultRateLimitConfig.Window
+	}
+	return &RateLimiter{
+		config: config,
+		store:  store,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+func (rl *RateLimiter) Allow(key RateLimitKey) RateLimitResult {
+	name, rule := rl.config.rule(key)
+	result := RateLimitResult{Allowed: true, Limit: rule.MaxRequests, Window: rule.Window}
+	if rule.MaxRequests <= 0 && rule.MaxBytes <= 0 {
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rateLimitStoreTimeout)
+	defer cancel()
+	taken, err := rl.store.Take(ctx, key.IP+"|"+name, rule, rl.now())
+	if err != nil {
+		// 存储不可用时放行，不影响正常访问
+		rl.storeError(err)
+		result.Limit = 0
+		return result
+	}
+
+	// 流量检查：当前窗口的流量已用完时拒绝（整形模式下改为限速）
+	if rule.MaxBytes > 0 && taken.Bytes >= float64(rule.MaxBytes) {
+		if rl.config.TrafficMode == TrafficShape {
+			// 整形模式：不拒绝，响应限速
+			result.ThrottleRate = rl.config.ShapeRate
+			rl.logger.Printf("[Traffic] IP: %s, Rule: %s, Current: %.0f bytes, Limit: %d bytes - THROTTLED to %d bytes/s",
+				key.IP, name, taken.Bytes, rule.MaxBytes, rl.config.ShapeRate)
+		} else {
+			result.Allowed = false
+			result.Reason = "Bandwidth Limit Exceeded"
+			result.RetryAfter = taken.BytesRetryAfter
+			rl.logger.Printf("[Traffic] IP: %s, Rule: %s, Current: %.0f bytes, Limit: %d bytes - BLOCKED",
+				key.IP, name, taken.Bytes, rule.MaxBytes)
+		}
+	}
+
+	if rule.MaxRequests <= 0 {
+		return result
+	}
+
+	rate := float64(rule.MaxRequests) / rule.Window.Seconds()
+	if result.Allowed && !taken.Allowed {
+		result.Allowed = false
+		result.Reason = "Too Many Requests"
+		result.RetryAfter = time.Duration((1 - taken.Tokens) / rate * float64(time.Second))
+		rl.logger.Printf("[RateLimit] IP: %s, Rule: %s, Limit: %d requests/%s - BLOCKED",
+			key.IP, name, rule.MaxRequests, rule.Window)
+	}
+
+	result.Remaining = int(taken.Tokens)
+	result.Reset = time.Duration((float64(rule.Burst) - taken.Tokens) / rate * float64(time.Second))
+	return result
+}
+
+func (rl *RateLimiter) RecordTraffic(key RateLimitKey, bytes int64) {
+	name, rule := rl.config.rule(key)
+	if rule.MaxBytes <= 0 || bytes <= 0 {
+		return
+	}
+
+	// 响应已经发出，超过限制也要记录，之后的请求会被拒绝
+	ctx, cancel := context.WithTimeout(context.Background(), rateLimitStoreTimeout)
+	defer cancel()
+	if err := rl.store.AddBytes(ctx, key.IP+"|"+name, rule, bytes, rl.now()); err != nil {
+		rl.storeError(err)
+	}
+}
+
+// storeError 记录存储错误，每 10 秒最多一条
+func (rl *RateLimiter) storeError(err error) {
+	now := time.Now().UnixNano()
+	last := rl.lastStoreError.Load()
+	if now-last < int64(10*time.Second) || !rl.lastStoreError.CompareAndSwap(last, now) {
+		return
+	}
+	rl.logger.Printf("[RateLimit] Store error, requests are allowed: %v", err)
+}
+
+// setRateLimitHeaders 设置 RateLimit-* 响应头（draft-ietf-httpapi-ratelimit-headers），
+// 被拒绝时同时设置 Retry-After，前端据此退避
+func setRateLimitHeaders(header http.Header, result RateLimitResult) {
+	if result.Limit > 0 {
+		header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", result.Limit, ceilSeconds(result.Window)))
+	}
+	if !result.Allowed {
+		header.Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(result.RetryAfter))))
+	}
+}
+
+// ceilSeconds 向上取整到秒
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// trafficWriter 统计实际写出的响应字节数（压缩之后）和响应状态，用于流量记录和自动封禁
+type trafficWriter struct {
+	http.ResponseWriter
+	written int64
+	status  int
+}
+
+func (tw *trafficWriter) WriteHeader(status int) {
+	if tw.status == 0 && status >= 200 {
+		tw.status = status
+	}
+	tw.ResponseWriter.WriteHeader(status)
+}
+
+func (tw *trafficWriter) Write(p []byte) (int, error) {
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	n, err := tw.ResponseWriter.Write(p)
+	tw.written += int64(n)
+	return n, err
+}
+
+func (tw *trafficWriter) Flush() {
+	if flusher, ok := tw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap 供 http.ResponseController 访问底层 ResponseWriter
+func (tw *trafficWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
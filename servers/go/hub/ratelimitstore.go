@@ -0,0 +1,165 @@
+package hub
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimitStore 保存速率限制的计数
+// 每个 key 对应一个令牌桶和一个滑动窗口流量计数；多个实例共享同一个存储时，限制对所有实例合计生效。
+// rule 已经过 RateLimitConfig 补全（Window > 0，Burst 已设置），时间由调用方传入
+type RateLimitStore interface {
+	// Take 按经过的时间补充令牌后尝试取一个令牌，同时返回流量窗口的使用情况，必须是原子操作
+	// MaxRequests 为 0 时不使用令牌桶，总是返回 Allowed=true；MaxBytes 为 0 时不统计流量
+	Take(ctx context.Context, key string, rule RateLimitRule, now time.Time) (StoreResult, error)
+	// AddBytes 把字节数计入 key 的流量窗口
+	AddBytes(ctx context.Context, key string, rule RateLimitRule, bytes int64, now time.Time) error
+}
+
+// StoreResult RateLimitStore.Take 的结果
+type StoreResult struct {
+	Allowed bool    // 是否取到了令牌
+	Tokens  float64 // 取令牌之后桶中剩余的令牌数
+
+	Bytes           float64       // 最近一个窗口长度内的估算字节数
+	BytesRetryAfter time.Duration // Bytes 达到 MaxBytes 时，降到限制以下所需的时间
+}
+
+// MemoryStore 进程内的计数存储
+// 每个 key 只保存固定大小的状态，超过 maxEntries 时按 LRU 淘汰
+type MemoryStore struct {
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	lru        *list.List // 最近使用的在前
+	maxEntries int
+}
+
+// limitEntry 一个计数（某个 IP 在某条规则下）的状态，内存占用固定
+type limitEntry struct {
+	key string
+
+	// 令牌桶
+	tokens     float64
+	lastRefill time.Time
+
+	// 滑动窗口计数
+	windowStart time.Time
+	prevBytes   int64
+	currBytes   int64
+}
+
+// NewMemoryStore 创建内存存储，maxEntries 为 0 时使用 10000
+func NewMemoryStore(maxEntries int) *MemoryStore {
+	if maxEntries <= 0 {
+		maxEntries = 10000
+	}
+	return &MemoryStore{
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		maxEntries: maxEntries,
+	}
+}
+
+func (m *MemoryStore) Take(ctx context.Context, key string, rule RateLimitRule, now time.Time) (StoreResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := m.entry(key, rule, now)
+	result := StoreResult{Allowed: true}
+
+	if rule.MaxBytes > 0 {
+		e.slideWindow(rule.Window, now)
+		result.Bytes = e.estimatedBytes(rule.Window, now)
+		if result.Bytes >= float64(rule.MaxBytes) {
+			result.BytesRetryAfter = e.bandwidthRetryAfter(rule, now)
+		}
+	}
+
+	if rule.MaxRequests > 0 {
+		// 令牌桶：按经过的时间补充令牌
+		rate := float64(rule.MaxRequests) / rule.Window.Seconds()
+		elapsed := max(0, now.Sub(e.lastRefill).Seconds())
+		e.tokens = math.Min(float64(rule.Burst), e.tokens+elapsed*rate)
+		e.lastRefill = now
+
+		if e.tokens >= 1 {
+			e.tokens--
+		} else {
+			result.Allowed = false
+		}
+		result.Tokens = e.tokens
+	}
+	return result, nil
+}
+
+func (m *MemoryStore) AddBytes(ctx context.Context, key string, rule RateLimitRule, bytes int64, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := m.entry(key, rule, now)
+	e.slideWindow(rule.Window, now)
+	e.currBytes += bytes
+	return nil
+}
+
+// entry 取出（或创建）计数并标记为最近使用，调用方需持有锁
+func (m *MemoryStore) entry(key string, rule RateLimitRule, now time.Time) *limitEntry {
+	if elem, ok := m.entries[key]; ok {
+		m.lru.MoveToFront(elem)
+		return elem.Value.(*limitEntry)
+	}
+
+	e := &limitEntry{
+		key:         key,
+		tokens:      float64(rule.Burst),
+		lastRefill:  now,
+		windowStart: now,
+	}
+	m.entries[key] = m.lru.PushFront(e)
+
+	// 淘汰最久未使用的计数
+	for m.lru.Len() > m.maxEntries {
+		oldest := m.lru.Back()
+		m.lru.Remove(oldest)
+		delete(m.entries, oldest.Value.(*limitEntry).key)
+	}
+	return e
+}
+
+// slideWindow 把滑动窗口推进到 now 所在的窗口
+func (e *limitEntry) slideWindow(window time.Duration, now time.Time) {
+	elapsed := now.Sub(e.windowStart)
+	if elapsed < window {
+		return
+	}
+	if elapsed < 2*window {
+		e.prevBytes = e.currBytes
+	} else {
+		e.prevBytes = 0
+	}
+	e.currBytes = 0
+	e.windowStart = e.windowStart.Add(elapsed / window * window)
+}
+
+// estimatedBytes 估算最近一个窗口长度内的字节数
+func (e *limitEntry) estimatedBytes(window time.Duration, now time.Time) float64 {
+	weight := 1 - float64(now.Sub(e.windowStart))/float64(window)
+	return float64(e.prevBytes)*weight + float64(e.currBytes)
+}
+
+// bandwidthRetryAfter 估算流量降到限制以下所需的时间
+func (e *limitEntry) bandwidthRetryAfter(rule RateLimitRule, now time.Time) time.Duration {
+	window := float64(rule.Window)
+	elapsed := float64(now.Sub(e.windowStart))
+	maxBytes := float64(rule.MaxBytes)
+
+	// 当前窗口已超过限制：等到下一个窗口中当前窗口的权重降下来
+	if float64(e.currBytes) >= maxBytes {
+		return time.Duration(window - elapsed + window*(1-maxBytes/float64(e.currBytes)))
+	}
+	// 上一个窗口的权重降下来即可
+	return time.Duration(window*(1-(maxBytes-float64(e.currBytes))/float64(e.prevBytes)) - elapsed)
+}
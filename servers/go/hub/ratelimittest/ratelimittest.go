@@ -0,0 +1,250 @@
+// Package ratelimittest 检查 hub.RateLimitStore 实现是否符合约定
+//
+// 用法与 testing/fstest.TestFS 类似，返回第一个不符合约定的地方：
+//
+//	func TestRedisStore(t *testing.T) {
+//		mr := miniredis.RunT(t)
+//		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+//		store := hub.NewRedisStore(client, "test:")
+//		if err := ratelimittest.TestStore(store); err != nil {
+//			t.Fatal(err)
+//		}
+//	}
+package ratelimittest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/firadio/jinja-hub/hub"
+)
+
+// TestStore 对存储执行一组检查，所有 key 都带有随机前缀，可以在已有数据的存储上运行
+// 检查使用固定的虚拟时间（精确到毫秒），不依赖真实时钟
+func TestStore(store hub.RateLimitStore) error {
+	prefix := "ratelimittest:" + strconv.FormatInt(time.Now().UnixNano(), 36) + ":"
+	checks := []struct {
+		name  string
+		check func(context.Context, hub.RateLimitStore, string) error
+	}{
+		{"token bucket", testTokenBucket},
+		{"refill", testRefill},
+		{"independent keys", testIndependentKeys},
+		{"unlimited requests", testUnlimitedRequests},
+		{"traffic window", testTrafficWindow},
+		{"traffic retry after", testTrafficRetryAfter},
+		{"concurrent take", testConcurrentTake},
+	}
+	ctx := context.Background()
+	for _, c := range checks {
+		if err := c.check(ctx, store, prefix+c.name+":"); err != nil {
+			return fmt.Errorf("ratelimittest: %s: %w", c.name, err)
+		}
+	}
+	return nil
+}
+
+// baseTime 虚拟时间的起点
+var baseTime = time.UnixMilli(1_700_000_000_000)
+
+func rule(window time.Duration, maxRequests, burst int, maxBytes int64) hub.RateLimitRule {
+	if burst == 0 {
+		burst = maxRequests
+	}
+	return hub.RateLimitRule{Window: window, MaxRequests: maxRequests, Burst: burst, MaxBytes: maxBytes}
+}
+
+// testTokenBucket 新的 key 可以连续取 Burst 个令牌，之后被拒绝
+func testTokenBucket(ctx context.Context, store hub.RateLimitStore, key string) error {
+	r := rule(10*time.Second, 5, 3, 0)
+	for i := 0; i < 3; i++ {
+		result, err := store.Take(ctx, key, r, baseTime)
+		if err != nil {
+			return err
+		}
+		if !result.Allowed {
+			return fmt.Errorf("take %d: rejected within burst", i+1)
+		}
+		if want := float64(2 - i); !near(result.Tokens, want, 0.01) {
+			return fmt.Errorf("take %d: tokens = %v, want %v", i+1, result.Tokens, want)
+		}
+	}
+	result, err := store.Take(ctx, key, r, baseTime)
+	if err != nil {
+		return err
+	}
+	if result.Allowed {
+		return fmt.Errorf("take beyond burst was allowed")
+	}
+	return nil
+}
+
+// testRefill 令牌按 MaxRequests/Window 的速率补充，且不超过 Burst
+func testRefill(ctx context.Context, store hub.RateLimitStore, key string) error {
+	r := rule(10*time.Second, 5, 0, 0) // 每 2 秒一个令牌
+	for i := 0; i < 5; i++ {
+		if _, err := store.Take(ctx, key, r, baseTime); err != nil {
+			return err
+		}
+	}
+	result, err := store.Take(ctx, key, r, baseTime.Add(time.Second))
+	if err != nil {
+		return err
+	}
+	if result.Allowed {
+		return fmt.Errorf("allowed after half a token was refilled")
+	}
+	result, err = store.Take(ctx, key, r, baseTime.Add(2*time.Second))
+	if err != nil {
+		return err
+	}
+	if !result.Allowed {
+		return fmt.Errorf("rejected after a token was refilled")
+	}
+	result, err = store.Take(ctx, key, r, baseTime.Add(time.Hour))
+	if err != nil {
+		return err
+	}
+	if !result.Allowed || !near(result.Tokens, 4, 0.01) {
+		return fmt.Errorf("after a long pause: allowed = %v, tokens = %v, want true, 4", result.Allowed, result.Tokens)
+	}
+	return nil
+}
+
+// testIndependentKeys 不同 key 的计数互不影响
+func testIndependentKeys(ctx context.Context, store hub.RateLimitStore, key string) error {
+	r := rule(10*time.Second, 1, 0, 1000)
+	if _, err := store.Take(ctx, key+"a", r, baseTime); err != nil {
+		return err
+	}
+	if err := store.AddBytes(ctx, key+"a", r, 5000, baseTime); err != nil {
+		return err
+	}
+	result, err := store.Take(ctx, key+"b", r, baseTime)
+	if err != nil {
+		return err
+	}
+	if !result.Allowed || result.Bytes != 0 {
+		return fmt.Errorf("key b: allowed = %v, bytes = %v, want true, 0", result.Allowed, result.Bytes)
+	}
+	return nil
+}
+
+// testUnlimitedRequests MaxRequests 为 0 时总是取到令牌
+func testUnlimitedRequests(ctx context.Context, store hub.RateLimitStore, key string) error {
+	r := rule(10*time.Second, 0, 0, 1000)
+	for i := 0; i < 100; i++ {
+		result, err := store.Take(ctx, key, r, baseTime)
+		if err != nil {
+			return err
+		}
+		if !result.Allowed {
+			return fmt.Errorf("take %d rejected without a request limit", i+1)
+		}
+	}
+	return nil
+}
+
+// testTrafficWindow 流量按滑动窗口估算：上一个窗口按剩余比例计入，两个窗口之后清零
+func testTrafficWindow(ctx context.Context, store hub.RateLimitStore, key string) error {
+	r := rule(10*time.Second, 0, 0, 1_000_000)
+	if _, err := store.Take(ctx, key, r, baseTime); err != nil {
+		return err
+	}
+	if err := store.AddBytes(ctx, key, r, 3000, baseTime.Add(time.Second)); err != nil {
+		return err
+	}
+	if err := store.AddBytes(ctx, key, r, 1000, baseTime.Add(2*time.Second)); err != nil {
+		return err
+	}
+
+	steps := []struct {
+		at   time.Duration
+		want float64
+	}{
+		{5 * time.Second, 4000},
+		{15 * time.Second, 2000}, // 上一个窗口还剩一半权重
+		{25 * time.Second, 0},
+	}
+	for _, step := range steps {
+		result, err := store.Take(ctx, key, r, baseTime.Add(step.at))
+		if err != nil {
+			return err
+		}
+		if !near(result.Bytes, step.want, 1) {
+			return fmt.Errorf("at %s: bytes = %v, want %v", step.at, result.Bytes, step.want)
+		}
+		if result.BytesRetryAfter != 0 {
+			return fmt.Errorf("at %s: retry after = %s under the limit", step.at, result.BytesRetryAfter)
+		}
+	}
+	return nil
+}
+
+// testTrafficRetryAfter 流量超出后给出降到限制以下的时间
+func testTrafficRetryAfter(ctx context.Context, store hub.RateLimitStore, key string) error {
+	r := rule(10*time.Second, 0, 0, 1000)
+	if err := store.AddBytes(ctx, key, r, 2000, baseTime); err != nil {
+		return err
+	}
+	result, err := store.Take(ctx, key, r, baseTime.Add(4*time.Second))
+	if err != nil {
+		return err
+	}
+	if !near(result.Bytes, 2000, 1) {
+		return fmt.Errorf("bytes = %v, want 2000", result.Bytes)
+	}
+	// 下一个窗口开始（6 秒后）再过半个窗口，上一个窗口的 2000 字节按一半计入
+	want := 11 * time.Second
+	if diff := result.BytesRetryAfter - want; diff < -10*time.Millisecond || diff > 10*time.Millisecond {
+		return fmt.Errorf("retry after = %s, want %s", result.BytesRetryAfter, want)
+	}
+	result, err = store.Take(ctx, key, r, baseTime.Add(4*time.Second+want+time.Millisecond))
+	if err != nil {
+		return err
+	}
+	if result.Bytes >= 1000 {
+		return fmt.Errorf("bytes = %v after retry after, want below 1000", result.Bytes)
+	}
+	return nil
+}
+
+// testConcurrentTake 并发取令牌时恰好 Burst 个成功
+func testConcurrentTake(ctx context.Context, store hub.RateLimitStore, key string) error {
+	r := rule(time.Hour, 20, 0, 0)
+	var wg sync.WaitGroup
+	var allowed atomic.Int64
+	errs := make(chan error, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := store.Take(ctx, key, r, baseTime)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if result.Allowed {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	if err := <-errs; err != nil {
+		return err
+	}
+	if n := allowed.Load(); n != 20 {
+		return fmt.Errorf("%d of 50 concurrent takes allowed, want 20", n)
+	}
+	return nil
+}
+
+func near(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance
+}
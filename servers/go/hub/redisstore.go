@@ -0,0 +1,144 @@
+package hub
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultRedisPrefix Redis 计数键的默认前缀
+const DefaultRedisPrefix = "jinja-hub:ratelimit:"
+
+// RedisStore 使用 Redis（或兼容 Redis 协议的服务）保存计数，供多个实例共享
+// 每个 key 是一个 hash，读改写在 Lua 脚本中完成，保证并发请求的原子性；
+// 时间由各实例传入，实例之间的时钟需要同步（NTP）
+type RedisStore struct {
+	client redis.Scripter
+	prefix string
+}
+
+// NewRedisStore 创建 Redis 存储，client 可以是 *redis.Client、*redis.ClusterClient 等，
+// prefix 为空时使用 DefaultRedisPrefix
+func NewRedisStore(client redis.Scripter, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = DefaultRedisPrefix
+	}
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// redisSlideWindow 两个脚本共用：读取状态并把滑动窗口推进到 now 所在的窗口
+// 时间单位为毫秒；hash 字段：tokens、last（上次补充令牌）、start（当前窗口开始）、prev、curr
+const redisSlideWindow = `
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local maxRequests = tonumber(ARGV[3])
+local burst = tonumber(ARGV[4])
+local maxBytes = tonumber(ARGV[5])
+local ttl = tonumber(ARGV[6])
+
+local state = redis.call('HMGET', key, 'tokens', 'last', 'start', 'prev', 'curr')
+local tokens = tonumber(state[1]) or burst
+local last = tonumber(state[2]) or now
+local start = tonumber(state[3]) or now
+local prev = tonumber(state[4]) or 0
+local curr = tonumber(state[5]) or 0
+
+local elapsed = now - start
+if elapsed >= window then
+	if elapsed < 2 * window then
+		prev = curr
+	else
+		prev = 0
+	end
+	curr = 0
+	start = start + math.floor(elapsed / window) * window
+end
+`
+
+// redisTakeScript 补充令牌并取一个令牌，返回 {allowed, tokens, bytes, bytesRetryAfter}
+// 浮点数以字符串返回，Redis 会把 Lua 数字截断为整数
+var redisTakeScript = redis.NewScript(redisSlideWindow + `
+local bytes = 0
+local retryAfter = 0
+if maxBytes > 0 then
+	local elapsedInWindow = now - start
+	bytes = prev * (1 - elapsedInWindow / window) + curr
+	if bytes >= maxBytes then
+		if curr >= maxBytes then
+			retryAfter = window - elapsedInWindow + window * (1 - maxBytes / curr)
+		else
+			retryAfter = window * (1 - (maxBytes - curr) / prev) - elapsedInWindow
+		end
+	end
+end
+
+local allowed = 1
+if maxRequests > 0 then
+	local rate = maxRequests / window
+	tokens = math.min(burst, tokens + math.max(0, now - last) * rate)
+	last = math.max(last, now)
+	if tokens >= 1 then
+		tokens = tokens - 1
+	else
+		allowed = 0
+	end
+end
+
+redis.call('HSET', key, 'tokens', tostring(tokens), 'last', last, 'start', start, 'prev', prev, 'curr', curr)
+redis.call('PEXPIRE', key, ttl)
+return {allowed, tostring(tokens), tostring(bytes), tostring(retryAfter)}
+`)
+
+// redisAddBytesScript 把 ARGV[7] 字节计入当前窗口
+var redisAddBytesScript = redis.NewScript(redisSlideWindow + `
+curr = curr + tonumber(ARGV[7])
+redis.call('HSET', key, 'start', start, 'prev', prev, 'curr', curr)
+if redis.call('HEXISTS', key, 'tokens') == 0 then
+	redis.call('HSET', key, 'tokens', tostring(tokens), 'last', last)
+end
+redis.call('PEXPIRE', key, ttl)
+return 1
+`)
+
+func (s *RedisStore) Take(ctx context.Context, key string, rule RateLimitRule, now time.Time) (StoreResult, error) {
+	reply, err := redisTakeScript.Run(ctx, s.client, []string{s.prefix + key}, redisArgs(rule, now)...).Slice()
+	if err != nil {
+		return StoreResult{}, err
+	}
+	allowed, _ := reply[0].(int64)
+	tokens, _ := strconv.ParseFloat(reply[1].(string), 64)
+	bytes, _ := strconv.ParseFloat(reply[2].(string), 64)
+	retryAfter, _ := strconv.ParseFloat(reply[3].(string), 64)
+	return StoreResult{
+		Allowed:         allowed == 1,
+		Tokens:          tokens,
+		Bytes:           bytes,
+		BytesRetryAfter: time.Duration(retryAfter * float64(time.Millisecond)),
+	}, nil
+}
+
+func (s *RedisStore) AddBytes(ctx context.Context, key string, rule RateLimitRule, bytes int64, now time.Time) error {
+	args := append(redisArgs(rule, now), bytes)
+	return redisAddBytesScript.Run(ctx, s.client, []string{s.prefix + key}, args...).Err()
+}
+
+// redisArgs 脚本参数：now、window、maxRequests、burst、maxBytes、ttl（毫秒）
+// 计数在令牌补满且流量窗口过去之后才会过期，过期后的状态与重新创建的相同
+func redisArgs(rule RateLimitRule, now time.Time) []interface{} {
+	ttl := 2 * rule.Window
+	if rule.MaxRequests > 0 {
+		refill := time.Duration(float64(rule.Window) * float64(rule.Burst) / float64(rule.MaxRequests))
+		ttl = max(ttl, refill)
+	}
+	return []interface{}{
+		now.UnixMilli(),
+		rule.Window.Milliseconds(),
+		rule.MaxRequests,
+		rule.Burst,
+		rule.MaxBytes,
+		ttl.Milliseconds() + 1,
+	}
+}
@@ -0,0 +1,20 @@
+package hub_test
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/firadio/jinja-hub/hub"
+	"github.com/firadio/jinja-hub/hub/ratelimittest"
+)
+
+func TestRedisStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	if err := ratelimittest.TestStore(hub.NewRedisStore(client, "test:")); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,114 @@
+package hub
+
+import (
+	"net/http"
+
+	"github.com/flosch/pongo2/v6"
+)
+
+// renderHomePage 渲染首页（所有站点列表）
+// origin 为 协议://Host，经 pageOrigin 过滤后用于生成绝对 URL
+func (s *Server) renderHomePage(w http.ResponseWriter, r *http.Request, state *siteState, origin string) {
+	origin = pageOrigin(state, origin)
+	key := renderKey{page: "_home", origin: origin}
+	if page := state.renderCache.get(key); page != nil {
+		s.sendRenderedPage(w, r, page)
+		return
+	}
+
+	// 首页模板在加载站点状态时已预编译
+	if state.homeTemplate == nil {
+		s.logger.Printf("Home template error: %v", state.homeErr)
+		http.Error(w, "Home template error: "+state.homeErr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// 构建上下文
+	ctx := pongo2.Context{
+		"platform":  state.homePlatform,
+		"sites":     state.homeSites,
+		"root_path": s.prefix,
+		"root_url":  origin + s.prefix,
+	}
+
+	// 执行模板
+	html, err := state.homeTemplate.ExecuteBytes(ctx)
+	if err != nil {
+		s.logger.Printf("Home render error: %v", err)
+		http.Error(w, "Home render error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// 缓存并返回 HTML (带 gzip 压缩)
+	page := newRenderedPage(html)
+	state.renderCache.put(key, page)
+	s.sendRenderedPage(w, r, page)
+}
+
+// renderSitePageWithBasePath 渲染站点页面，指定 base_path
+// origin 为 协议://Host，经 pageOrigin 过滤后用于生成绝对 URL
+func (s *Server) renderSitePageWithBasePath(w http.ResponseWriter, r *http.Request, state *siteState, siteName, pageName, basePath, origin string) {
+	urlOrigin := pageOrigin(state, origin)
+	key := renderKey{site: siteName, page: pageName, basePath: basePath, origin: urlOrigin}
+	if cached := state.renderCache.get(key); cached != nil {
+		s.sendRenderedPage(w, r, cached)
+		return
+	}
+
+	// 获取站点数据
+	site, exists := state.sites[siteName]
+	if !exists {
+		http.NotFound(w, r)
+		return
+	}
+
+	// 查找预编译的页面模板，不存在即 404
+	page, exists := site.pages[pageName]
+	if !exists {
+		http.NotFound(w, r)
+		return
+	}
+	if page.err != nil {
+		s.logger.Printf("Template error: %v", page.err)
+		http.Error(w, "Template error: "+page.err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// 构建上下文，只有 base_path 相关的部分随请求变化
+	ctx := pongo2.Context{
+		"config":    site.configFor(basePath),
+		"page":      page.object,
+		"site":      site.info,
+		"site_name": siteName,
+		"platform":  state.sitesConfig.Platform,
+		"all_sites": state.allSites,
+		"base_path": basePath,
+		"root_path": s.prefix,
+		"base_url":  urlOrigin + basePath,
+		"root_url":  urlOrigin + s.prefix,
+	}
+
+	// 资产变化页面随查询参数和快照变化，不缓存
+	dynamic := page.object["inventory"] == true
+	if dynamic {
+		ctx["inventory"] = s.inventoryPageData(r, state, siteName, origin)
+	}
+
+	// 执行模板
+	html, err := page.template.ExecuteBytes(ctx)
+	if err != nil {
+		s.logger.Printf("Render error: %v", err)
+		http.Error(w, "Render error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// 缓存并返回 HTML (带 gzip 压缩)
+	rendered := newRenderedPage(html)
+	if !dynamic {
+		state.renderCache.put(key, rendered)
+	} else {
+		// 内容取决于登录的用户，共享缓存不能保存
+		w.Header().Set("Cache-Control", "private, no-store")
+	}
+	s.sendRenderedPage(w, r, rendered)
+}
@@ -0,0 +1,62 @@
+package hub
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// unlimited 不做速率限制的 Limiter，基准测试只测量渲染
+type unlimited struct{}
+
+func (unlimited) Allow(RateLimitKey) RateLimitResult { return RateLimitResult{Allowed: true} }
+func (unlimited) RecordTraffic(RateLimitKey, int64)  {}
+
+// newBenchmarkServer 使用仓库中的 sites 目录创建 Server，关闭速率限制
+func newBenchmarkServer(b *testing.B) *Server {
+	b.Helper()
+	srv, err := New(Options{
+		SitesFS: os.DirFS("../../../sites"),
+		Limiter: unlimited{},
+		Logger:  log.New(io.Discard, "", 0),
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return srv
+}
+
+func benchmarkRequest(b *testing.B, srv *Server, target string, uncached bool) {
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080"+target, nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if uncached {
+			srv.getState().renderCache = newRenderCache()
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			b.Fatalf("GET %s: status = %d", target, rec.Code)
+		}
+	}
+}
+
+func BenchmarkRenderSitePage(b *testing.B) {
+	benchmarkRequest(b, newBenchmarkServer(b), "/aliyun/ecs_instances.html", true)
+}
+
+func BenchmarkRenderSitePageCached(b *testing.B) {
+	benchmarkRequest(b, newBenchmarkServer(b), "/aliyun/ecs_instances.html", false)
+}
+
+func BenchmarkRenderHomePage(b *testing.B) {
+	benchmarkRequest(b, newBenchmarkServer(b), "/", true)
+}
+
+func BenchmarkRenderHomePageCached(b *testing.B) {
+	benchmarkRequest(b, newBenchmarkServer(b), "/", false)
+}
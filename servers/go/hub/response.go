@@ -0,0 +1,42 @@
+package hub
+
+import (
+	"strings"
+)
+
+// 可压缩的 MIME 类型
+var compressibleTypes = map[string]bool{
+	"text/html":              true,
+	"text/css":               true,
+	"application/javascript": true,
+	"application/json":       true,
+	"image/svg+xml":          true,
+	"text/plain":             true,
+	"application/xml":        true,
+	"text/xml":               true,
+}
+
+// 检查内容类型是否可压缩
+func isCompressible(contentType string) bool {
+	// 移除参数部分 (如 charset)
+	parts := strings.Split(contentType, ";")
+	return compressibleTypes[strings.TrimSpace(parts[0])]
+}
+
+// 添加 charset 到 Content-Type
+func addCharset(contentType string) string {
+	// 如果已经包含 charset，不重复添加
+	if strings.Contains(contentType, "charset") {
+		return contentType
+	}
+
+	// 对文本类型添加 charset=UTF-8
+	textTypes := []string{"text/", "application/json", "application/javascript", "application/xml"}
+	for _, prefix := range textTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return contentType + "; charset=UTF-8"
+		}
+	}
+
+	return contentType
+}
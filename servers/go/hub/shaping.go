@@ -0,0 +1,136 @@
+package hub
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// shapedWriteTimeout 限速写出时每个分片的写超时
+// 限速后的响应可能远超 http.Server 的 WriteTimeout，因此每写一个分片就顺延写超时
+const shapedWriteTimeout = 30 * time.Second
+
+// shaperIdleTimeout 客户端限速器闲置多久后回收
+const shaperIdleTimeout = time.Minute
+
+// egressShaper 出口带宽整形：全局总带宽和每个 IP 的带宽
+// 同一 IP 的并发响应共享一个限速器，多开连接不能绕过限速
+type egressShaper struct {
+	global *rate.Limiter // 为 nil 表示不限制总带宽
+
+	mu        sync.Mutex
+	clients   map[string]*shapedClient
+	lastSweep time.Time
+}
+
+type shapedClient struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+// newEgressShaper 创建整形器，egressRate 为全局总带宽（字节/秒），0 表示不限制
+func newEgressShaper(egressRate int64) *egressShaper {
+	s := &egressShaper{clients: make(map[string]*shapedClient)}
+	if egressRate > 0 {
+		s.global = newByteLimiter(egressRate)
+	}
+	return s
+}
+
+// newByteLimiter 创建按字节计数的限速器，桶容量约为 1/4 秒的流量，至少 4KB
+func newByteLimiter(bytesPerSecond int64) *rate.Limiter {
+	burst := bytesPerSecond / 4
+	if burst < 4096 {
+		burst = 4096
+	}
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), int(burst))
+}
+
+// client 取出（或创建）IP 的限速器，速率变化时更新
+func (s *egressShaper) client(ip string, bytesPerSecond int64) *rate.Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > shaperIdleTimeout {
+		s.lastSweep = now
+		for key, c := range s.clients {
+			if now.Sub(c.lastUsed) > shaperIdleTimeout {
+				delete(s.clients, key)
+			}
+		}
+	}
+
+	c, ok := s.clients[ip]
+	if !ok {
+		c = &shapedClient{limiter: newByteLimiter(bytesPerSecond)}
+		s.clients[ip] = c
+	} else if c.limiter.Limit() != rate.Limit(bytesPerSecond) {
+		c.limiter.SetLimit(rate.Limit(bytesPerSecond))
+		c.limiter.SetBurst(newByteLimiter(bytesPerSecond).Burst())
+	}
+	c.lastUsed = now
+	return c.limiter
+}
+
+// wrap 按需为响应加上限速，throttleRate 为该客户端的限速（字节/秒），0 表示不单独限速
+func (s *egressShaper) wrap(w http.ResponseWriter, r *http.Request, ip string, throttleRate int64) http.ResponseWriter {
+	var limiters []*rate.Limiter
+	if throttleRate > 0 {
+		limiters = append(limiters, s.client(ip, throttleRate))
+	}
+	if s.global != nil {
+		limiters = append(limiters, s.global)
+	}
+	if len(limiters) == 0 {
+		return w
+	}
+	return &throttledWriter{ResponseWriter: w, ctx: r.Context(), limiters: limiters}
+}
+
+// throttledWriter 按限速器分片写出响应
+type throttledWriter struct {
+	http.ResponseWriter
+	ctx      context.Context
+	limiters []*rate.Limiter
+}
+
+func (tw *throttledWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := len(p)
+		for _, limiter := range tw.limiters {
+			if burst := limiter.Burst(); chunk > burst {
+				chunk = burst
+			}
+		}
+		for _, limiter := range tw.limiters {
+			if err := limiter.WaitN(tw.ctx, chunk); err != nil {
+				return written, err
+			}
+		}
+
+		http.NewResponseController(tw.ResponseWriter).SetWriteDeadline(time.Now().Add(shapedWriteTimeout))
+		n, err := tw.ResponseWriter.Write(p[:chunk])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[chunk:]
+	}
+	return written, nil
+}
+
+func (tw *throttledWriter) Flush() {
+	if flusher, ok := tw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap 供 http.ResponseController 访问底层 ResponseWriter
+func (tw *throttledWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
@@ -0,0 +1,99 @@
+package hub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// 内置签名算法的名称，站点在 config.json 的 api.signer 中选择
+const (
+	SignerAliyunRPC = "aliyun-rpc" // 阿里云 RPC 风格（HMAC-SHA1），未配置时的默认值
+	SignerAWSV4     = "aws-sigv4"  // AWS Signature Version 4（Query API）
+	SignerTC3       = "tc3"        // 腾讯云 TC3-HMAC-SHA256
+)
+
+// APICall 签名代理收到的一次 API 调用
+type APICall struct {
+	Endpoint string            // 已替换 {region} 的 API 地址
+	Service  string            // 服务名，api.{product}.service，未配置时为产品名
+	Version  string            // API 版本
+	Action   string            // 接口名
+	Region   string            // 地域，可能为空
+	Params   map[string]string // 查询参数和表单参数，同名参数只取第一个值
+	Body     []byte            // JSON 请求体（请求的 Content-Type 为 application/json 时），为 nil 表示没有
+}
+
+// Signer 云服务商的请求签名算法
+// 各家 API 的参数位置和公共参数不同，Signer 负责按服务商的调用方式构造请求并签名
+type Signer interface {
+	// RegionParam 请求中表示地域的参数名，用于替换 endpoint 中的 {region}
+	RegionParam() string
+
+	// NewRequest 构造并签名发往 call.Endpoint 的请求；参数不合法时返回错误
+	NewRequest(ctx context.Context, call APICall, credential Credential) (*http.Request, error)
+}
+
+// builtinSigners 内置的签名算法
+func builtinSigners() map[string]Signer {
+	return map[string]Signer{
+		SignerAliyunRPC: AliyunRPCSigner{},
+		SignerAWSV4:     AWSV4Signer{},
+		SignerTC3:       TC3Signer{},
+	}
+}
+
+// hmacSHA256 计算 HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// sha256Hex 计算 SHA-256 的十六进制摘要
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// sortedParamKeys 按参数名排序
+func sortedParamKeys(params map[string]string) []string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// rfc3986Escape 按 RFC 3986 编码：只保留 A-Z a-z 0-9 - _ . ~，空格编码为 %20
+func rfc3986Escape(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			b.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{c})))
+		}
+	}
+	return b.String()
+}
+
+// canonicalQuery 按参数名排序并以 RFC 3986 编码的查询字符串
+func canonicalQuery(params map[string]string) string {
+	var b strings.Builder
+	for i, key := range sortedParamKeys(params) {
+		if i > 0 {
+			b.WriteByte('&')
+		}
+		b.WriteString(rfc3986Escape(key))
+		b.WriteByte('=')
+		b.WriteString(rfc3986Escape(params[key]))
+	}
+	return b.String()
+}
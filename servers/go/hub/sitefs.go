@@ -0,0 +1,114 @@
+package hub
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+// OverlayFS 按顺序在多个文件系统中查找文件，排在前面的优先
+// 用于让磁盘上的站点目录覆盖编译进二进制的站点文件
+type OverlayFS []fs.FS
+
+func (o OverlayFS) Open(name string) (fs.File, error) {
+	for _, fsys := range o {
+		file, err := fsys.Open(name)
+		if err == nil {
+			return file, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir 合并各层中同一目录的列表，同名条目以排在前面的层为准，结果按文件名排序
+func (o OverlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	var entries []fs.DirEntry
+	seen := make(map[string]bool)
+	found := false
+	for _, fsys := range o {
+		layer, err := fs.ReadDir(fsys, name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		found = true
+		for _, entry := range layer {
+			if !seen[entry.Name()] {
+				seen[entry.Name()] = true
+				entries = append(entries, entry)
+			}
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return entries, nil
+}
+
+// NewSitesFS 根据磁盘目录和内嵌文件构建站点文件系统
+// 磁盘目录存在时逐个文件优先使用磁盘版本，不存在时完全使用内嵌文件；embedded 可以为 nil
+func NewSitesFS(dir string, embedded fs.FS) fs.FS {
+	var layers OverlayFS
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		layers = append(layers, os.DirFS(dir))
+	}
+	if embedded != nil {
+		layers = append(layers, embedded)
+	}
+	if len(layers) == 1 {
+		return layers[0]
+	}
+	return layers
+}
+
+// fsTemplateLoader 从 fs.FS 加载 pongo2 模板
+// 模板中 include/extends 的路径都相对于 root 解析，与 LocalFilesystemLoader 设置 baseDir 时一致
+type fsTemplateLoader struct {
+	fsys fs.FS
+	root string
+}
+
+func newFSTemplateLoader(fsys fs.FS, root string) (*fsTemplateLoader, error) {
+	info, err := fs.Stat(fsys, root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "open", Path: root, Err: errors.New("not a directory")}
+	}
+	return &fsTemplateLoader{fsys: fsys, root: root}, nil
+}
+
+// Abs 解析模板路径；pongo2 会对已解析的路径再次调用 Abs，已带 root 前缀的路径原样返回
+func (l *fsTemplateLoader) Abs(base, name string) string {
+	if strings.HasPrefix(name, l.root+"/") {
+		return name
+	}
+	return path.Join(l.root, name)
+}
+
+func (l *fsTemplateLoader) Get(name string) (io.Reader, error) {
+	file, err := l.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// 读入内存后立即关闭文件，pongo2 不会关闭返回的 Reader
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
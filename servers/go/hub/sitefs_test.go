@@ -0,0 +1,53 @@
+package hub
+
+import (
+	"errors"
+	"io/fs"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestOverlayFSPartialOverride(t *testing.T) {
+	disk := fstest.MapFS{
+		"aliyun/templates/pages/index.html": {Data: []byte("disk index")},
+		"aliyun/templates/pages/extra.html": {Data: []byte("disk extra")},
+	}
+	embedded := fstest.MapFS{
+		"aliyun/templates/pages/index.html": {Data: []byte("embedded index")},
+		"aliyun/templates/pages/login.html": {Data: []byte("embedded login")},
+		"aws/templates/pages/index.html":    {Data: []byte("embedded aws")},
+	}
+	overlay := OverlayFS{disk, embedded}
+
+	entries, err := fs.ReadDir(overlay, "aliyun/templates/pages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if want := []string{"extra.html", "index.html", "login.html"}; !slices.Equal(names, want) {
+		t.Fatalf("ReadDir = %v, want %v", names, want)
+	}
+
+	for name, want := range map[string]string{
+		"aliyun/templates/pages/index.html": "disk index",
+		"aliyun/templates/pages/login.html": "embedded login",
+		"aws/templates/pages/index.html":    "embedded aws",
+	} {
+		data, err := fs.ReadFile(overlay, name)
+		if err != nil || string(data) != want {
+			t.Errorf("ReadFile(%s) = %q, %v; want %q", name, data, err, want)
+		}
+	}
+
+	// 只存在于内嵌层的目录也能列出
+	if entries, err := fs.ReadDir(overlay, "aws/templates/pages"); err != nil || len(entries) != 1 {
+		t.Errorf("ReadDir(aws/templates/pages) = %v, %v", entries, err)
+	}
+	if _, err := fs.ReadDir(overlay, "missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadDir(missing) error = %v, want ErrNotExist", err)
+	}
+}
@@ -0,0 +1,359 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flosch/pongo2/v6"
+)
+
+// siteState 站点运行时状态快照
+// 每次加载都会构建一个全新的快照，构建成功后整体原子替换，
+// 请求处理过程中只读取同一个快照，不会看到构建了一半的数据。
+// 模板在加载时预编译，渲染所需的上下文数据也在此时计算好，请求时只需合并 base_path 等少量值
+type siteState struct {
+	sitesConfig  SitesConfig
+	siteConfigs  map[string]Config
+	sites        map[string]*siteData
+	domainToSite map[string]string
+	allSites     []map[string]interface{} // 按 order 排序的站点列表（all_sites）
+	renderCache  *renderCache
+	loadedAt     time.Time
+
+	// 首页模板和上下文，homeTemplate 为 nil 时 homeErr 说明原因
+	homeTemplate *pongo2.Template
+	homeErr      error
+	homePlatform PlatformInfo
+	homeSites    []map[string]interface{}
+}
+
+// siteData 单个站点的预计算数据
+type siteData struct {
+	info       SiteInfo
+	config     Config
+	pagesArray []map[string]interface{} // 按 order 排序的页面列表（config.pages_array）
+	pages      map[string]*pageData
+	apiProxy   bool // 服务端配置了凭证，前端通过 api/proxy/ 调用 API
+
+	// configs 按 base_path 缓存带 base_path 的 config，base_path 只有路径模式和域名模式几种取值
+	configs sync.Map
+}
+
+// pageData 预编译的页面，编译失败时 template 为 nil，err 记录原因
+type pageData struct {
+	template *pongo2.Template
+	err      error
+	object   map[string]interface{} // 模板中的 page 变量
+}
+
+// configFor 返回带 base_path 和 pages_array 的 config（只读，多个请求共享）
+func (d *siteData) configFor(basePath string) map[string]interface{} {
+	if cached, ok := d.configs.Load(basePath); ok {
+		return cached.(map[string]interface{})
+	}
+
+	// 复制 config 并添加 base_path（不修改原始配置）
+	config := make(map[string]interface{}, len(d.config)+4)
+	for k, v := range d.config {
+		config[k] = v
+	}
+	config["base_path"] = basePath
+	config["api_export"] = true // 前端可以通过 api/export/ 导出表格
+	if d.apiProxy {
+		config["api_proxy"] = true
+	}
+	if d.pagesArray != nil {
+		config["pages_array"] = d.pagesArray
+	}
+
+	actual, _ := d.configs.LoadOrStore(basePath, config)
+	return actual.(map[string]interface{})
+}
+
+// getState 返回当前生效的状态快照
+func (s *Server) getState() *siteState {
+	return s.state.Load()
+}
+
+// loadSiteState 从站点文件系统构建新的状态快照
+// 任何一个启用站点的配置或模板目录有问题都会返回错误，不会产生部分可用的快照
+func loadSiteState(fsys fs.FS) (*siteState, error) {
+	sitesConfig, err := loadSitesConfig(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("sites.json: %w", err)
+	}
+
+	state := &siteState{
+		sitesConfig:  sitesConfig,
+		siteConfigs:  make(map[string]Config),
+		sites:        make(map[string]*siteData),
+		domainToSite: make(map[string]string),
+		allSites:     sortedSites(sitesConfig.Sites, "id"),
+		renderCache:  newRenderCache(),
+		loadedAt:     time.Now(),
+	}
+
+	// 加载所有启用的站点配置和模板
+	for siteName, siteInfo := range sitesConfig.Sites {
+		if !siteInfo.Enabled {
+			continue
+		}
+
+		// 加载站点配置
+		config, err := loadSiteConfig(fsys, siteName)
+		if err != nil {
+			return nil, fmt.Errorf("site %s: %w", siteName, err)
+		}
+		state.siteConfigs[siteName] = config
+
+		// 初始化站点模板集并预编译所有页面
+		loader, err := newFSTemplateLoader(fsys, path.Join(siteName, "templates"))
+		if err != nil {
+			return nil, fmt.Errorf("site %s: %w", siteName, err)
+		}
+		pages, err := compilePages(fsys, pongo2.NewSet(siteName, loader), siteName, config)
+		if err != nil {
+			return nil, fmt.Errorf("site %s: %w", siteName, err)
+		}
+
+		state.sites[siteName] = &siteData{
+			info:       siteInfo,
+			config:     config,
+			pagesArray: sortedPages(config.Pages()),
+			pages:      pages,
+		}
+
+		// 构建域名映射
+		for _, domain := range siteInfo.Domains {
+			state.domainToSite[domain] = siteName
+		}
+	}
+
+	// 预编译首页模板，首页模板有问题不影响站点加载
+	state.loadHome(fsys)
+
+	// 加载自定义域名映射
+	for domain, siteName := range sitesConfig.DomainMapping {
+		state.domainToSite[domain] = siteName
+	}
+
+	return state, nil
+}
+
+// compilePages 预编译站点 templates/pages 目录下的所有页面模板
+// 单个页面编译失败只影响该页面（请求时返回 500），不影响站点其他页面
+func compilePages(fsys fs.FS, templateSet *pongo2.TemplateSet, siteName string, config Config) (map[string]*pageData, error) {
+	pages := make(map[string]*pageData)
+	entries, err := fs.ReadDir(fsys, path.Join(siteName, "templates", "pages"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return pages, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pageConfigs := config.Pages()
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".html") {
+			continue
+		}
+		pageName := strings.TrimSuffix(entry.Name(), ".html")
+
+		tmpl, err := templateSet.FromFile("pages/" + entry.Name())
+
+		// 构建 page 对象,确保包含 name 字段
+		pageObject := make(map[string]interface{})
+		for k, v := range pageConfigs[pageName] {
+			pageObject[k] = v
+		}
+		pageObject["name"] = pageName
+
+		pages[pageName] = &pageData{template: tmpl, err: err, object: pageObject}
+	}
+	return pages, nil
+}
+
+// loadHome 预编译首页模板并计算首页上下文
+func (state *siteState) loadHome(fsys fs.FS) {
+	// 构建默认平台信息
+	state.homePlatform = state.sitesConfig.Platform
+	if state.homePlatform.Name == "" {
+		state.homePlatform.Name = "Jinja Hub"
+		state.homePlatform.Description = "开放式前端开发平台"
+	}
+	state.homeSites = sortedSites(state.sitesConfig.Sites, "name")
+
+	homeLoader, err := newFSTemplateLoader(fsys, "_home/templates")
+	if err != nil {
+		state.homeErr = err
+		return
+	}
+	state.homeTemplate, state.homeErr = pongo2.NewSet("_home", homeLoader).FromFile("index.html")
+}
+
+// sortedSites 将 sites 转换为按 order、名称排序的数组，keyName 为站点标识的字段名
+func sortedSites(sites map[string]SiteInfo, keyName string) []map[string]interface{} {
+	type siteEntry struct {
+		id   string
+		info SiteInfo
+	}
+	var entries []siteEntry
+	for siteID, siteInfo := range sites {
+		entries = append(entries, siteEntry{id: siteID, info: siteInfo})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].info.Order != entries[j].info.Order {
+			return entries[i].info.Order < entries[j].info.Order
+		}
+		return entries[i].id < entries[j].id
+	})
+
+	sitesArray := []map[string]interface{}{}
+	for _, entry := range entries {
+		sitesArray = append(sitesArray, map[string]interface{}{
+			keyName: entry.id,
+			"info": map[string]interface{}{
+				"name":        entry.info.Name,
+				"description": entry.info.Description,
+				"icon":        entry.info.Icon,
+				"enabled":     entry.info.Enabled,
+				"category":    entry.info.Category,
+				"path":        entry.info.Path,
+				"domains":     entry.info.Domains,
+				"order":       entry.info.Order,
+			},
+		})
+	}
+	return sitesArray
+}
+
+// sortedPages 将 pages 转换为按 order、key 排序的数组，没有页面时返回 nil
+func sortedPages(pagesMap map[string]map[string]interface{}) []map[string]interface{} {
+	if len(pagesMap) == 0 {
+		return nil
+	}
+
+	type pageEntry struct {
+		key   string
+		value map[string]interface{}
+	}
+	var pageEntries []pageEntry
+	for key, val := range pagesMap {
+		pageEntries = append(pageEntries, pageEntry{key: key, value: val})
+	}
+
+	sort.Slice(pageEntries, func(i, j int) bool {
+		orderI, okI := pageEntries[i].value["order"].(float64)
+		orderJ, okJ := pageEntries[j].value["order"].(float64)
+		if !okI {
+			orderI = 0
+		}
+		if !okJ {
+			orderJ = 0
+		}
+		if orderI != orderJ {
+			return orderI < orderJ
+		}
+		return pageEntries[i].key < pageEntries[j].key
+	})
+
+	sortedPages := make([]map[string]interface{}, 0)
+	for _, entry := range pageEntries {
+		sortedPages = append(sortedPages, map[string]interface{}{
+			"key":   entry.key,
+			"value": entry.value,
+		})
+	}
+	return sortedPages
+}
+
+// Reload 重新加载站点配置和模板并原子替换
+// 加载失败时返回错误并保留之前的快照继续提供服务
+func (s *Server) Reload() error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	state, err := loadSiteState(s.sitesFS)
+	if err != nil {
+		if s.getState() != nil {
+			s.logger.Printf("[Reload] Rejected, keeping previous config: %v", err)
+		}
+		return err
+	}
+	if reloader, ok := s.credentials.(credentialReloader); ok {
+		if err := reloader.Reload(); err != nil {
+			s.logger.Printf("[Reload] Credentials not reloaded, keeping previous: %v", err)
+		}
+	}
+	if s.credentials != nil {
+		for siteName, site := range state.sites {
+			_, site.apiProxy = s.credentials.Credential(siteName)
+		}
+	}
+
+	if s.state.Swap(state) != nil {
+		s.logger.Printf("[Reload] Sites reloaded (%d enabled)", len(state.siteConfigs))
+	}
+	return nil
+}
+
+// Watch 定期检查磁盘上的站点目录，配置或模板发生变化时自动重载，直到 ctx 结束
+// 使用轮询而不是系统通知，保证在各平台和网络文件系统上行为一致
+func (s *Server) Watch(ctx context.Context, dir string, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	last := sitesFingerprint(dir)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current := sitesFingerprint(dir)
+		if current == last {
+			continue
+		}
+		last = current
+		s.logger.Println("[Reload] Change detected in sites directory")
+		s.Reload()
+	}
+}
+
+// sitesFingerprint 计算站点目录中配置和模板文件的指纹
+// 静态资源和 CDN 缓存不影响渲染，跳过以减少开销
+func sitesFingerprint(dir string) string {
+	var fingerprint []byte
+	filepath.WalkDir(dir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if name := d.Name(); name == "static" || name == "_static" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		fingerprint = fmt.Appendf(fingerprint, "%s|%d|%d\n", filePath, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return string(fingerprint)
+}
@@ -0,0 +1,207 @@
+package hub
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MIME 类型覆盖表
+// mime.TypeByExtension 会读取系统的 MIME 数据库（Windows 注册表、/etc/mime.types），
+// 不同机器结果不一致，常用的前端资源类型在这里固定下来
+var mimeOverrides = map[string]string{
+	".js":          "application/javascript",
+	".mjs":         "application/javascript",
+	".css":         "text/css",
+	".html":        "text/html",
+	".json":        "application/json",
+	".map":         "application/json",
+	".webmanifest": "application/manifest+json",
+	".svg":         "image/svg+xml",
+	".ico":         "image/x-icon",
+	".webp":        "image/webp",
+	".woff":        "font/woff",
+	".woff2":       "font/woff2",
+	".ttf":         "font/ttf",
+	".otf":         "font/otf",
+	".eot":         "application/vnd.ms-fontobject",
+	".wasm":        "application/wasm",
+	".txt":         "text/plain",
+	".xml":         "application/xml",
+}
+
+// contentTypeByExtension 根据扩展名确定 MIME 类型
+func contentTypeByExtension(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if contentType, ok := mimeOverrides[ext]; ok {
+		return contentType
+	}
+	if contentType := mime.TypeByExtension(ext); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// contentHashes 缓存没有修改时间的文件（内嵌文件）的内容哈希，这类文件不会变化
+var contentHashes sync.Map
+
+// serveStaticFile 发送站点静态文件
+func (s *Server) serveStaticFile(w http.ResponseWriter, r *http.Request, filePath string) {
+	// 规范化路径并检查是否在站点的 static 目录内（防止路径遍历攻击）
+	cleanPath := path.Clean(filePath)
+	segments := strings.SplitN(cleanPath, "/", 3)
+	if !fs.ValidPath(cleanPath) || len(segments) != 3 || segments[1] != "static" {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	s.serveFile(w, r, s.sitesFS, cleanPath)
+}
+
+// serveFile 以 http.ServeContent 的语义发送 fsys 中的文件
+// 支持 Range、If-Modified-Since、If-None-Match 和 HEAD，内容从文件流式读取。
+// 可压缩的文本类型按 Accept-Encoding 协商编码：优先使用同目录下的预压缩文件
+// （name.br / name.zst / name.gz），否则在非 Range 请求时由 compressWriter 边读边压缩，
+// 这里只负责为协商出的编码设置对应的 ETag，保证条件请求能正确命中
+func (s *Server) serveFile(w http.ResponseWriter, r *http.Request, fsys fs.FS, name string) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	contentType := contentTypeByExtension(name)
+	header := w.Header()
+	header.Set("Content-Type", addCharset(contentType))
+
+	encoding := ""
+	var sidecars map[string]fs.File
+	if isCompressible(contentType) {
+		addVary(header, "Accept-Encoding")
+		sidecars = openSidecars(fsys, name, info)
+		defer func() {
+			for _, sidecar := range sidecars {
+				sidecar.Close()
+			}
+		}()
+
+		// Range 请求需要原始字节偏移，只能使用预压缩文件
+		var offered []string
+		for _, enc := range contentEncodings {
+			if sidecars[enc.name] != nil || (info.Size() >= minCompressSize && r.Header.Get("Range") == "") {
+				offered = append(offered, enc.name)
+			}
+		}
+		encoding = negotiateEncoding(r.Header.Get("Accept-Encoding"), offered)
+	}
+
+	content, err := readSeeker(file)
+	if err != nil {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+	etag, err := fileETag(name, info, content)
+	if err != nil {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+
+	// 不同编码是不同的表示，使用不同的 ETag
+	if encoding != "" {
+		etag = strings.TrimSuffix(etag, `"`) + "-" + encoding + `"`
+	}
+	header.Set("ETag", etag)
+
+	// 预压缩文件作为独立的表示直接发送，Range 针对压缩后的字节
+	if sidecar := sidecars[encoding]; sidecar != nil {
+		sidecarInfo, err := sidecar.Stat()
+		if err != nil {
+			http.Error(w, "Server error", http.StatusInternalServerError)
+			return
+		}
+		sidecarContent, err := readSeeker(sidecar)
+		if err != nil {
+			http.Error(w, "Server error", http.StatusInternalServerError)
+			return
+		}
+		header.Set("Content-Encoding", encoding)
+		// 设置了 Content-Encoding 时 ServeContent 不会为完整响应设置 Content-Length
+		header.Set("Content-Length", strconv.FormatInt(sidecarInfo.Size(), 10))
+		http.ServeContent(w, r, name, info.ModTime(), sidecarContent)
+		return
+	}
+
+	// 没有预压缩文件时由 compressWriter 边读边压缩
+	http.ServeContent(w, r, name, info.ModTime(), content)
+}
+
+// openSidecars 打开 name 的预压缩文件
+// 比原文件旧的预压缩文件视为过期（原文件修改后尚未重新生成），不会使用
+func openSidecars(fsys fs.FS, name string, info fs.FileInfo) map[string]fs.File {
+	var sidecars map[string]fs.File
+	for _, enc := range contentEncodings {
+		sidecar, err := fsys.Open(name + enc.ext)
+		if err != nil {
+			continue
+		}
+		sidecarInfo, err := sidecar.Stat()
+		if err != nil || sidecarInfo.IsDir() || sidecarInfo.ModTime().Before(info.ModTime()) {
+			sidecar.Close()
+			continue
+		}
+		if sidecars == nil {
+			sidecars = make(map[string]fs.File)
+		}
+		sidecars[enc.name] = sidecar
+	}
+	return sidecars
+}
+
+// readSeeker 内嵌文件等不支持 Seek 的实现读入内存
+func readSeeker(file fs.File) (io.ReadSeeker, error) {
+	if content, ok := file.(io.ReadSeeker); ok {
+		return content, nil
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// fileETag 生成强 ETag：有修改时间的文件使用 大小-修改时间，否则使用内容哈希
+func fileETag(name string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	if !info.ModTime().IsZero() {
+		return fmt.Sprintf(`"%x-%x"`, info.Size(), info.ModTime().UnixNano()), nil
+	}
+
+	if cached, ok := contentHashes.Load(name); ok {
+		return cached.(string), nil
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+	contentHashes.Store(name, etag)
+	return etag, nil
+}
@@ -0,0 +1,444 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	statusMaxFilterIDs = 100              // idsParam 一次最多传入的 ID 数，超过时查询整个地域
+	statusHeartbeat    = 15 * time.Second // 心跳注释的间隔，防止反向代理因空闲断开连接
+)
+
+// StatusStreamConfig 资源状态推送（/{site}/api/status/{table}）：服务端定期查询被关注资源的状态，
+// 通过 Server-Sent Events 推送变化；同一站点、表格和地域的所有连接共享一个轮询
+type StatusStreamConfig struct {
+	Interval time.Duration // 轮询间隔，0 表示 3 秒
+	Settle   time.Duration // 资源一直处于最终状态时，经过多久视为已稳定，0 表示 15 秒
+	Timeout  time.Duration // 每个连接的最长时间，0 表示 10 分钟
+	MaxIDs   int           // 每个连接最多关注的资源数，0 表示 50
+}
+
+// Validate 检查配置是否可用
+func (c StatusStreamConfig) Validate() error {
+	if c.Interval < 0 || c.Settle < 0 || c.Timeout < 0 || c.MaxIDs < 0 {
+		return fmt.Errorf("interval, settle, timeout and max_ids must not be negative")
+	}
+	if c.Interval > 0 && c.Interval < time.Second {
+		return fmt.Errorf("interval must be at least 1s")
+	}
+	return nil
+}
+
+// withDefaults 填充未配置的字段
+func (c StatusStreamConfig) withDefaults() StatusStreamConfig {
+	if c.Interval == 0 {
+		c.Interval = 3 * time.Second
+	}
+	if c.Settle == 0 {
+		c.Settle = 15 * time.Second
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Minute
+	}
+	if c.MaxIDs == 0 {
+		c.MaxIDs = 50
+	}
+	return c
+}
+
+// statusTable 站点配置 tables.{table} 中状态推送使用的字段
+type statusTable struct {
+	StatusField string          // statusField，状态字段的路径，例如 Status
+	Terminal    map[string]bool // terminalStatus，最终状态；未配置时所有状态都是最终状态
+	IDsParam    string          // idsParam，按 ID 过滤的查询参数（JSON 数组），例如 InstanceIds；未配置时查询整个地域
+}
+
+// statusTableFor 读取站点配置中表格的状态字段，表格必须同时配置了 rowKey
+func statusTableFor(config Config, table string) (fanOutTable, statusTable, bool) {
+	listing, ok := fanOutTableFor(config, table)
+	if !ok || listing.RowKey == "" {
+		return fanOutTable{}, statusTable{}, false
+	}
+	tables, _ := config["tables"].(map[string]interface{})
+	entry, _ := tables[table].(map[string]interface{})
+	var status statusTable
+	status.StatusField, _ = entry["statusField"].(string)
+	if status.StatusField == "" {
+		return fanOutTable{}, statusTable{}, false
+	}
+	status.IDsParam, _ = entry["idsParam"].(string)
+	terminal, _ := entry["terminalStatus"].([]interface{})
+	if len(terminal) > 0 {
+		status.Terminal = make(map[string]bool, len(terminal))
+		for _, value := range terminal {
+			if name, ok := value.(string); ok {
+				status.Terminal[name] = true
+			}
+		}
+	}
+	return listing, status, true
+}
+
+// isTerminal 状态是否为最终状态
+func (t statusTable) isTerminal(status string) bool {
+	return t.Terminal == nil || t.Terminal[status]
+}
+
+// statusPollerKey 共享轮询的键，同一站点、表格和地域只有一个轮询
+type statusPollerKey struct {
+	site, table, region string
+}
+
+// statusPoller 一个地域的共享轮询，查询所有订阅者关注的资源的并集；
+// 最后一个订阅者退出时停止。subscribers 和 latest 由 Server.statusMu 保护
+type statusPoller struct {
+	key         statusPollerKey
+	cancel      context.CancelFunc
+	wake        chan struct{} // 有新的资源需要查询时立即轮询一次
+	subscribers map[*statusSubscriber]bool
+	latest      *statusPoll
+	lastError   string // 上一次轮询的错误码，只由轮询的 goroutine 访问，错误变化时才记录日志
+}
+
+// statusSubscriber 一个连接的订阅，ch 只保留最新一次的轮询结果
+type statusSubscriber struct {
+	ids []string
+	ch  chan *statusPoll
+}
+
+// statusPoll 一次轮询的结果；covered 为 nil 表示查询了整个地域
+type statusPoll struct {
+	rows     map[string]map[string]interface{} // rowKey 到行
+	covered  map[string]bool
+	complete bool // 没有失败也没有被截断，查不到的资源可以视为已不存在
+	code     string
+	message  string
+}
+
+// covers 该次轮询是否查询了资源
+func (p *statusPoll) covers(id string) bool {
+	return p.covered == nil || p.covered[id]
+}
+
+// subscribeStatus 订阅一个地域中资源的状态，没有对应的轮询时创建一个；
+// 已有的轮询结果覆盖了这些资源时立即发送给订阅者，否则立即轮询一次
+func (s *Server) subscribeStatus(key statusPollerKey, ids []string) (*statusPoller, *statusSubscriber) {
+	sub := &statusSubscriber{ids: ids, ch: make(chan *statusPoll, 1)}
+
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	poller, ok := s.statusPollers[key]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		poller = &statusPoller{
+			key:         key,
+			cancel:      cancel,
+			wake:        make(chan struct{}, 1),
+			subscribers: make(map[*statusSubscriber]bool),
+		}
+		s.statusPollers[key] = poller
+		go s.runStatusPoller(ctx, poller)
+	}
+	poller.subscribers[sub] = true
+
+	covered := poller.latest != nil
+	for _, id := range ids {
+		if covered && !poller.latest.covers(id) {
+			covered = false
+		}
+	}
+	if covered {
+		sub.ch <- poller.latest
+	} else {
+		select {
+		case poller.wake <- struct{}{}:
+		default:
+		}
+	}
+	return poller, sub
+}
+
+// unsubscribeStatus 取消订阅，最后一个订阅者退出时停止轮询
+func (s *Server) unsubscribeStatus(poller *statusPoller, sub *statusSubscriber) {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	delete(poller.subscribers, sub)
+	if len(poller.subscribers) == 0 {
+		poller.cancel()
+		if s.statusPollers[poller.key] == poller {
+			delete(s.statusPollers, poller.key)
+		}
+	}
+}
+
+// runStatusPoller 按 StatusStream.Interval 轮询，把结果发送给所有订阅者，直到 ctx 结束
+func (s *Server) runStatusPoller(ctx context.Context, poller *statusPoller) {
+	ticker := time.NewTicker(s.statusStream.Interval)
+	defer ticker.Stop()
+	for {
+		s.statusMu.Lock()
+		var ids []string
+		seen := make(map[string]bool)
+		for sub := range poller.subscribers {
+			for _, id := range sub.ids {
+				if !seen[id] {
+					seen[id] = true
+					ids = append(ids, id)
+				}
+			}
+		}
+		s.statusMu.Unlock()
+
+		poll := s.pollStatus(ctx, poller, ids)
+		if ctx.Err() != nil {
+			return
+		}
+
+		s.statusMu.Lock()
+		poller.latest = poll
+		for sub := range poller.subscribers {
+			// 只保留最新的结果：订阅者还没有读取上一次的结果时丢弃它
+			select {
+			case <-sub.ch:
+			default:
+			}
+			sub.ch <- poll
+		}
+		s.statusMu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-poller.wake:
+		}
+	}
+}
+
+// statusPollJob 按当前的站点配置和凭证准备一次轮询，返回聚合查询的任务和表格的 idsParam
+// 每次轮询都重新读取，重载配置或轮换凭证后正在运行的轮询立即使用新的凭证
+func (s *Server) statusPollJob(key statusPollerKey) (fanOutJob, string, *apiError) {
+	config := s.getState().siteConfigs[key.site]
+	_, table, ok := statusTableFor(config, key.table)
+	if !ok {
+		return fanOutJob{}, "", &apiError{http.StatusNotFound, "UnknownTable", "Table is not configured for status updates: " + key.table}
+	}
+	credential, ok := s.credentials.Credential(key.site)
+	if !ok {
+		return fanOutJob{}, "", &apiError{http.StatusNotFound, "CredentialNotFound", "No server-side credential for site: " + key.site}
+	}
+	job, apiErr := s.newFanOutJob(config, key.site, credential, key.table)
+	return job, table.IDsParam, apiErr
+}
+
+// pollStatus 查询一个地域中的资源；配置了 idsParam 且资源数不超过 statusMaxFilterIDs 时按 ID 过滤
+func (s *Server) pollStatus(ctx context.Context, poller *statusPoller, ids []string) *statusPoll {
+	poll := &statusPoll{rows: make(map[string]map[string]interface{})}
+	job, idsParam, apiErr := s.statusPollJob(poller.key)
+	if apiErr != nil {
+		poll.code, poll.message = apiErr.Code, apiErr.Message
+		if apiErr.Code != poller.lastError {
+			s.logger.Printf("[Status] %s/%s %s: %s: %s", poller.key.site, poller.key.table, poller.key.region, apiErr.Code, apiErr.Message)
+		}
+		poller.lastError = apiErr.Code
+		return poll
+	}
+	if idsParam != "" && len(ids) <= statusMaxFilterIDs {
+		encoded, _ := json.Marshal(ids)
+		job.params[idsParam] = string(encoded)
+		poll.covered = make(map[string]bool, len(ids))
+		for _, id := range ids {
+			poll.covered[id] = true
+		}
+	}
+
+	result := s.fanOutRegion(ctx, job, poller.key.region)
+	for _, item := range result.items {
+		if row, ok := item.(map[string]interface{}); ok {
+			if key := inventoryRowKey(row, job.table.RowKey); key != "" {
+				poll.rows[key] = row
+			}
+		}
+	}
+	poll.complete = result.Code == "" && !result.Truncated
+	poll.code, poll.message = result.Code, result.Message
+	if result.Code != "" && result.Code != poller.lastError && ctx.Err() == nil {
+		s.logger.Printf("[Status] %s/%s %s: %s: %s", poller.key.site, poller.key.table, poller.key.region, result.Code, result.Message)
+	}
+	poller.lastError = result.Code
+	return poll
+}
+
+// statusEvent status 事件：资源的当前状态，Done 表示已到达最终状态、之后不再推送
+type statusEvent struct {
+	ID     string                 `json:"id"`
+	Status string                 `json:"status"`
+	Done   bool                   `json:"done"`
+	Row    map[string]interface{} `json:"row"`
+}
+
+// statusWatch 一个连接中一个资源的推送进度
+type statusWatch struct {
+	first, last string
+	seen, done  bool
+}
+
+// handleAPIStatus 处理 /{site}/api/status/{table}?region=&ids=a,b：
+// 以 Server-Sent Events 推送资源状态（表格的 statusField，按 rowKey 对应资源），事件有：
+//
+//	status  {id, status, done, row} 首次查询到资源和状态变化时推送
+//	gone    {id} 资源已不存在（地域查询完整时才会判断）
+//	error   {Code, Message} 轮询失败，之后继续重试
+//	end     {reason} 所有资源都到达最终状态（done）或超过 StatusStream.Timeout（timeout），之后服务端关闭连接
+//
+// 资源处于 terminalStatus 中的状态，并且与首次查询到的状态不同或已经保持了 StatusStream.Settle 时视为最终状态，
+// 这样在操作刚发出、云服务 API 还没有反映出中间状态时不会立即结束
+func (s *Server) handleAPIStatus(w http.ResponseWriter, r *http.Request, state *siteState, siteName, origin, tableName string) {
+	if s.credentials == nil {
+		http.NotFound(w, r)
+		return
+	}
+	credential, ok := s.credentials.Credential(siteName)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeAPIProxyError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "Only GET is allowed")
+		return
+	}
+	if _, ok := s.authenticateAPI(w, r, siteName, origin); !ok {
+		return
+	}
+
+	config := state.siteConfigs[siteName]
+	_, table, ok := statusTableFor(config, tableName)
+	if !ok {
+		writeAPIProxyError(w, http.StatusNotFound, "UnknownTable", "Table is not configured for status updates: "+tableName)
+		return
+	}
+	region := r.URL.Query().Get("region")
+	if !apiProxyRegionPattern.MatchString(region) {
+		writeAPIProxyError(w, http.StatusBadRequest, "InvalidRegion", "Invalid region: "+region)
+		return
+	}
+	var ids []string
+	seen := make(map[string]bool)
+	for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		writeAPIProxyError(w, http.StatusBadRequest, "MissingIds", "ids is required")
+		return
+	}
+	if len(ids) > s.statusStream.MaxIDs {
+		writeAPIProxyError(w, http.StatusBadRequest, "TooManyIds", fmt.Sprintf("At most %d ids can be watched at once", s.statusStream.MaxIDs))
+		return
+	}
+	if _, apiErr := s.newFanOutJob(config, siteName, credential, tableName); apiErr != nil {
+		writeAPIProxyError(w, apiErr.Status, apiErr.Code, apiErr.Message)
+		return
+	}
+
+	// 连接会持续到所有资源到达最终状态，写超时延长到 Timeout 之后
+	controller := http.NewResponseController(w)
+	controller.SetWriteDeadline(time.Now().Add(s.statusStream.Timeout + time.Minute))
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	send := func(event string, data interface{}) bool {
+		payload, _ := json.Marshal(data)
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+			return false
+		}
+		return controller.Flush() == nil
+	}
+	// 浏览器的 EventSource 断开后自动重连，重连间隔不短于轮询间隔
+	fmt.Fprintf(w, "retry: %d\n\n", max(s.statusStream.Interval, 5*time.Second).Milliseconds())
+	if controller.Flush() != nil {
+		return
+	}
+
+	poller, sub := s.subscribeStatus(statusPollerKey{siteName, tableName, region}, ids)
+	defer s.unsubscribeStatus(poller, sub)
+
+	start := time.Now()
+	timeout := time.NewTimer(s.statusStream.Timeout)
+	defer timeout.Stop()
+	heartbeat := time.NewTicker(statusHeartbeat)
+	defer heartbeat.Stop()
+
+	watches := make(map[string]*statusWatch, len(ids))
+	for _, id := range ids {
+		watches[id] = &statusWatch{}
+	}
+	lastError := ""
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-timeout.C:
+			send("end", map[string]string{"reason": "timeout"})
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil || controller.Flush() != nil {
+				return
+			}
+		case poll := <-sub.ch:
+			if poll.code != "" && poll.code != lastError {
+				if !send("error", map[string]string{"Code": poll.code, "Message": poll.message}) {
+					return
+				}
+			}
+			lastError = poll.code
+
+			pending := 0
+			for _, id := range ids {
+				watch := watches[id]
+				if watch.done {
+					continue
+				}
+				row, found := poll.rows[id]
+				switch {
+				case !poll.covers(id):
+				case !found && poll.complete:
+					watch.done = true
+					if !send("gone", map[string]string{"id": id}) {
+						return
+					}
+				case found:
+					status := cellText(lookupFieldPath(row, table.StatusField), "")
+					if !watch.seen {
+						watch.first, watch.seen = status, true
+					}
+					watch.done = table.isTerminal(status) && (status != watch.first || time.Since(start) >= s.statusStream.Settle)
+					if status != watch.last || watch.done {
+						watch.last = status
+						if !send("status", statusEvent{ID: id, Status: status, Done: watch.done, Row: row}) {
+							return
+						}
+					}
+				}
+				if !watch.done {
+					pending++
+				}
+			}
+			if pending == 0 {
+				send("end", map[string]string{"reason": "done"})
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package hub
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+// rotatingCredentials 可以在运行中更换的凭证
+type rotatingCredentials struct {
+	mu         sync.Mutex
+	credential Credential
+}
+
+func (c *rotatingCredentials) Credential(site string) (Credential, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.credential, true
+}
+
+func (c *rotatingCredentials) rotate(credential Credential) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.credential = credential
+}
+
+func TestStatusPollerUsesCurrentCredential(t *testing.T) {
+	var keyIDs []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keyIDs = append(keyIDs, r.URL.Query().Get("AccessKeyId"))
+		io.WriteString(w, `{"Instances": {"Instance": [{"InstanceId": "i-1", "Status": "Running"}]}, "TotalCount": 1}`)
+	}))
+	defer upstream.Close()
+
+	credentials := &rotatingCredentials{credential: Credential{AccessKeyID: "LTAI-old", AccessKeySecret: "old"}}
+	srv, err := New(Options{
+		SitesFS: fstest.MapFS{
+			"sites.json": {Data: []byte(`{"sites": {"aliyun": {"name": "Aliyun", "enabled": true}}}`)},
+			"aliyun/config.json": {Data: []byte(`{
+				"api": {"ecs": {"version": "2014-05-26", "endpoint": "` + upstream.URL + `"}},
+				"tables": {"ecs_instances": {"product": "ecs", "apiFunction": "DescribeInstances", "dataPath": "Instances.Instance",
+					"rowKey": "InstanceId", "statusField": "Status", "idsParam": "InstanceIds"}}
+			}`)},
+			"aliyun/templates/pages/index.html": {Data: []byte(`index`)},
+			"_home/templates/index.html":        {Data: []byte(`home`)},
+		},
+		Credentials: credentials,
+		Logger:      log.New(io.Discard, "", 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	poller := &statusPoller{key: statusPollerKey{"aliyun", "ecs_instances", "cn-hangzhou"}}
+	if poll := srv.pollStatus(context.Background(), poller, []string{"i-1"}); poll.code != "" || poll.rows["i-1"] == nil {
+		t.Fatalf("first poll = %+v", poll)
+	}
+	credentials.rotate(Credential{AccessKeyID: "LTAI-new", AccessKeySecret: "new"})
+	if poll := srv.pollStatus(context.Background(), poller, []string{"i-1"}); poll.code != "" {
+		t.Fatalf("second poll = %+v", poll)
+	}
+	if len(keyIDs) != 2 || keyIDs[0] != "LTAI-old" || keyIDs[1] != "LTAI-new" {
+		t.Fatalf("AccessKeyId per poll = %v, want [LTAI-old LTAI-new]", keyIDs)
+	}
+}
@@ -0,0 +1,29 @@
+package hub
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// 腾讯云 API 文档“签名方法 v3”中 CVM DescribeInstances 的示例（请求体中的中文按文档写成 \u 转义）
+func TestSignTC3(t *testing.T) {
+	credential := Credential{AccessKeyID: "AKIDz8krbsJ5yKBZQpn74WFkmLPx3EXAMPLE", AccessKeySecret: "Gu5t9xGARNpq86cd98joQYCN3EXAMPLE"}
+	body := []byte(`{"Limit": 1, "Filters": [{"Values": ["\u672a\u547d\u540d"], "Name": "instance-name"}]}`)
+	req, err := http.NewRequest(http.MethodPost, "https://cvm.tencentcloudapi.com/", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	SignTC3(req, body, credential, "cvm", time.Unix(1551113065, 0))
+	want := "TC3-HMAC-SHA256 Credential=AKIDz8krbsJ5yKBZQpn74WFkmLPx3EXAMPLE/2019-02-25/cvm/tc3_request, " +
+		"SignedHeaders=content-type;host, Signature=72e494ea809ad7a8c8f7a4507b9bddcbaa8e581f516e8da2f66e2c5a96525168"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization = %s\nwant %s", got, want)
+	}
+	if got := req.Header.Get("X-TC-Timestamp"); got != "1551113065" {
+		t.Errorf("X-TC-Timestamp = %s", got)
+	}
+}
@@ -0,0 +1,422 @@
+package hub
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// 保险库文件格式版本
+const vaultVersion = 1
+
+// 派生密钥的方式
+const (
+	vaultKDFScrypt  = "scrypt"
+	vaultKDFKeyFile = "keyfile"
+)
+
+// scrypt 参数，交互式使用的推荐值（约 100ms）
+const (
+	vaultScryptN = 1 << 15
+	vaultScryptR = 8
+	vaultScryptP = 1
+)
+
+// vaultCheckText 用固定明文校验密钥，空保险库也能发现口令错误
+const vaultCheckText = "jinja-hub-vault"
+
+// vaultKeyFileMinBytes 密钥文件的最小长度
+const vaultKeyFileMinBytes = 32
+
+var (
+	// ErrVaultKey 口令或密钥文件与保险库不匹配
+	ErrVaultKey = errors.New("vault: wrong passphrase or key file")
+	// ErrCredentialExists 添加的凭证名称已存在
+	ErrCredentialExists = errors.New("vault: credential already exists")
+	// ErrCredentialNotFound 凭证名称不存在
+	ErrCredentialNotFound = errors.New("vault: credential not found")
+)
+
+var vaultNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// vaultCheckName 校验块加密时使用的附加数据，凭证不能使用这个名称，否则其密文可以冒充校验块
+const vaultCheckName = "check"
+
+// VaultKey 打开保险库的密钥来源，Passphrase 和 KeyFile 二选一
+// 保险库创建时使用哪一种，之后打开时就必须使用同一种
+type VaultKey struct {
+	Passphrase string // 使用 scrypt 从口令派生密钥
+	KeyFile    string // 密钥文件，至少 32 字节的随机内容（例如 head -c 32 /dev/urandom）
+}
+
+// VaultEntry 凭证的元数据，不包含密钥
+type VaultEntry struct {
+	Name        string    `json:"name"`
+	AccessKeyID string    `json:"access_key_id"` // 打码后的 AccessKey ID
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"` // 最后一次轮换的时间
+}
+
+// vaultFile 保险库文件的格式，[]byte 字段以 base64 保存
+type vaultFile struct {
+	Version int                   `json:"version"`
+	KDF     string                `json:"kdf"`
+	Salt    []byte                `json:"salt,omitempty"`
+	N       int                   `json:"n,omitempty"`
+	R       int                   `json:"r,omitempty"`
+	P       int                   `json:"p,omitempty"`
+	Check   []byte                `json:"check"`
+	Entries map[string]vaultEntry `json:"entries"`
+}
+
+// vaultEntry 一条加密的凭证：nonce + AES-GCM 密文，附加数据为凭证名称，密文不能挪用到其他名称下
+type vaultEntry struct {
+	Data      []byte    `json:"data"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// vaultSecret 加密前的明文，Credential 的 JSON 编码会隐去密钥，这里单独定义
+type vaultSecret struct {
+	AccessKeyID     string `json:"access_key_id"`
+	AccessKeySecret string `json:"access_key_secret"`
+	SecurityToken   string `json:"security_token,omitempty"`
+}
+
+// Vault 加密保存的命名凭证（AES-256-GCM）
+// 凭证只在内存中解密，文件中除名称和时间外都是密文；Vault 实现了 CredentialSource，
+// 配合 SiteCredentials 让签名代理只通过名称引用凭证
+type Vault struct {
+	path string
+	key  VaultKey
+
+	mu          sync.RWMutex
+	aead        cipher.AEAD
+	file        vaultFile
+	credentials map[string]Credential
+}
+
+// OpenVault 打开保险库文件并解密所有凭证，文件不存在时创建空的保险库（第一次修改时写入磁盘）
+func OpenVault(filePath string, key VaultKey) (*Vault, error) {
+	if (key.Passphrase == "") == (key.KeyFile == "") {
+		return nil, errors.New("vault: exactly one of passphrase and key file is required")
+	}
+	v := &Vault{path: filePath, key: key}
+	if err := v.Reload(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Reload 重新读取保险库文件，用于在其他进程（命令行）修改后生效；失败时保留之前的凭证
+func (v *Vault) Reload() error {
+	file, err := readVaultFile(v.path)
+	if err != nil {
+		return err
+	}
+	if file == nil {
+		file, err = newVaultFile(v.key)
+		if err != nil {
+			return err
+		}
+	}
+
+	aead, err := vaultCipher(*file, v.key)
+	if err != nil {
+		return err
+	}
+	if check, err := vaultOpen(aead, file.Check, vaultCheckName); err != nil || string(check) != vaultCheckText {
+		return ErrVaultKey
+	}
+
+	credentials := make(map[string]Credential, len(file.Entries))
+	for name, entry := range file.Entries {
+		plaintext, err := vaultOpen(aead, entry.Data, name)
+		if err != nil {
+			return fmt.Errorf("vault: %s: %w", name, err)
+		}
+		var secret vaultSecret
+		if err := json.Unmarshal(plaintext, &secret); err != nil {
+			return fmt.Errorf("vault: %s: %w", name, err)
+		}
+		credentials[name] = Credential{
+			AccessKeyID:     secret.AccessKeyID,
+			AccessKeySecret: secret.AccessKeySecret,
+			SecurityToken:   secret.SecurityToken,
+			Name:            name,
+		}
+	}
+
+	v.mu.Lock()
+	v.aead = aead
+	v.file = *file
+	v.credentials = credentials
+	v.mu.Unlock()
+	return nil
+}
+
+// Lookup 按名称返回解密后的凭证
+func (v *Vault) Lookup(name string) (Credential, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	credential, ok := v.credentials[name]
+	return credential, ok
+}
+
+// List 返回所有凭证的元数据，按名称排序
+func (v *Vault) List() []VaultEntry {
+	v.mu.RLock()
+	entries := make([]VaultEntry, 0, len(v.file.Entries))
+	for name, entry := range v.file.Entries {
+		entries = append(entries, VaultEntry{
+			Name:        name,
+			AccessKeyID: maskAccessKeyID(v.credentials[name].AccessKeyID),
+			CreatedAt:   entry.CreatedAt,
+			UpdatedAt:   entry.UpdatedAt,
+		})
+	}
+	v.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries
+}
+
+// Add 添加凭证并写入文件，名称已存在时返回 ErrCredentialExists
+func (v *Vault) Add(name string, credential Credential) error {
+	return v.put(name, credential, false)
+}
+
+// Rotate 替换已有的凭证并写入文件，名称不存在时返回 ErrCredentialNotFound
+func (v *Vault) Rotate(name string, credential Credential) error {
+	return v.put(name, credential, true)
+}
+
+func (v *Vault) put(name string, credential Credential, replace bool) error {
+	if !vaultNamePattern.MatchString(name) || name == vaultCheckName {
+		return fmt.Errorf("vault: invalid credential name %q (letters, digits, '.', '_' and '-', up to 64 characters, not %q)", name, vaultCheckName)
+	}
+	if credential.AccessKeyID == "" || credential.AccessKeySecret == "" {
+		return errors.New("vault: access key id and secret are required")
+	}
+	plaintext, err := json.Marshal(vaultSecret{credential.AccessKeyID, credential.AccessKeySecret, credential.SecurityToken})
+	if err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	entry, exists := v.file.Entries[name]
+	switch {
+	case exists && !replace:
+		return fmt.Errorf("%w: %s", ErrCredentialExists, name)
+	case !exists && replace:
+		return fmt.Errorf("%w: %s", ErrCredentialNotFound, name)
+	}
+	now := time.Now().UTC()
+	if !exists {
+		entry.CreatedAt = now
+	}
+	entry.UpdatedAt = now
+	if entry.Data, err = vaultSeal(v.aead, plaintext, name); err != nil {
+		return err
+	}
+
+	file := v.file
+	file.Entries = make(map[string]vaultEntry, len(v.file.Entries)+1)
+	for k, e := range v.file.Entries {
+		file.Entries[k] = e
+	}
+	file.Entries[name] = entry
+	if err := writeVaultFile(v.path, file); err != nil {
+		return err
+	}
+	v.file = file
+	credential.Name = name
+	v.credentials[name] = credential
+	return nil
+}
+
+// Remove 删除凭证并写入文件，名称不存在时返回 ErrCredentialNotFound
+func (v *Vault) Remove(name string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if _, exists := v.file.Entries[name]; !exists {
+		return fmt.Errorf("%w: %s", ErrCredentialNotFound, name)
+	}
+	file := v.file
+	file.Entries = make(map[string]vaultEntry, len(v.file.Entries))
+	for k, e := range v.file.Entries {
+		if k != name {
+			file.Entries[k] = e
+		}
+	}
+	if err := writeVaultFile(v.path, file); err != nil {
+		return err
+	}
+	v.file = file
+	delete(v.credentials, name)
+	return nil
+}
+
+// Rekey 更换口令或密钥文件：用新密钥重新加密所有凭证并写入文件，之后必须使用新密钥打开
+func (v *Vault) Rekey(key VaultKey) error {
+	if (key.Passphrase == "") == (key.KeyFile == "") {
+		return errors.New("vault: exactly one of passphrase and key file is required")
+	}
+	file, err := newVaultFile(key)
+	if err != nil {
+		return err
+	}
+	aead, err := vaultCipher(*file, key)
+	if err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for name, entry := range v.file.Entries {
+		credential := v.credentials[name]
+		plaintext, err := json.Marshal(vaultSecret{credential.AccessKeyID, credential.AccessKeySecret, credential.SecurityToken})
+		if err != nil {
+			return err
+		}
+		if entry.Data, err = vaultSeal(aead, plaintext, name); err != nil {
+			return err
+		}
+		file.Entries[name] = entry
+	}
+	if err := writeVaultFile(v.path, *file); err != nil {
+		return err
+	}
+	v.key = key
+	v.aead = aead
+	v.file = *file
+	return nil
+}
+
+// readVaultFile 读取保险库文件，文件不存在时返回 nil
+func readVaultFile(filePath string) (*vaultFile, error) {
+	data, err := os.ReadFile(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file vaultFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("vault: %s: %w", filePath, err)
+	}
+	if file.Version != vaultVersion {
+		return nil, fmt.Errorf("vault: %s: unsupported version %d", filePath, file.Version)
+	}
+	return &file, nil
+}
+
+// writeVaultFile 写入保险库文件，只有所有者可以读写
+func writeVaultFile(filePath string, file vaultFile) error {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0700); err != nil {
+		return err
+	}
+	return writeFileAtomicPerm(filePath, data, 0600)
+}
+
+// newVaultFile 按密钥来源初始化空的保险库
+func newVaultFile(key VaultKey) (*vaultFile, error) {
+	file := &vaultFile{Version: vaultVersion, KDF: vaultKDFKeyFile, Entries: map[string]vaultEntry{}}
+	if key.Passphrase != "" {
+		file.KDF = vaultKDFScrypt
+		file.Salt = make([]byte, 16)
+		if _, err := rand.Read(file.Salt); err != nil {
+			return nil, err
+		}
+		file.N, file.R, file.P = vaultScryptN, vaultScryptR, vaultScryptP
+	}
+
+	aead, err := vaultCipher(*file, key)
+	if err != nil {
+		return nil, err
+	}
+	if file.Check, err = vaultSeal(aead, []byte(vaultCheckText), vaultCheckName); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// vaultCipher 按文件记录的方式派生 AES-256 密钥
+func vaultCipher(file vaultFile, key VaultKey) (cipher.AEAD, error) {
+	var derived []byte
+	switch file.KDF {
+	case vaultKDFScrypt:
+		if key.Passphrase == "" {
+			return nil, errors.New("vault: this vault is protected by a passphrase")
+		}
+		var err error
+		derived, err = scrypt.Key([]byte(key.Passphrase), file.Salt, file.N, file.R, file.P, 32)
+		if err != nil {
+			return nil, fmt.Errorf("vault: %w", err)
+		}
+	case vaultKDFKeyFile:
+		if key.KeyFile == "" {
+			return nil, errors.New("vault: this vault is protected by a key file")
+		}
+		content, err := os.ReadFile(key.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("vault: %w", err)
+		}
+		if len(content) < vaultKeyFileMinBytes {
+			return nil, fmt.Errorf("vault: key file must contain at least %d bytes", vaultKeyFileMinBytes)
+		}
+		sum := sha256.Sum256(content)
+		derived = sum[:]
+	default:
+		return nil, fmt.Errorf("vault: unsupported kdf %q", file.KDF)
+	}
+
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// vaultSeal 加密并在密文前附上随机 nonce
+func vaultSeal(aead cipher.AEAD, plaintext []byte, name string) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, []byte(name)), nil
+}
+
+// vaultOpen 解密 vaultSeal 的结果
+func vaultOpen(aead cipher.AEAD, data []byte, name string) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, ErrVaultKey
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(name))
+	if err != nil {
+		return nil, ErrVaultKey
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,179 @@
+package hub
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readTestVaultFile 读取保险库文件的原始内容
+func readTestVaultFile(t *testing.T, path string) vaultFile {
+	t.Helper()
+	file, err := readVaultFile(path)
+	if err != nil || file == nil {
+		t.Fatalf("readVaultFile: %v", err)
+	}
+	return *file
+}
+
+// writeTestVaultFile 写回修改过的保险库文件
+func writeTestVaultFile(t *testing.T, path string, file vaultFile) {
+	t.Helper()
+	data, err := json.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVaultRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.json")
+	key := VaultKey{Passphrase: "correct horse"}
+	vault, err := OpenVault(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credential := Credential{AccessKeyID: "LTAI0000000000000001", AccessKeySecret: "secret-1", SecurityToken: "sts"}
+	if err := vault.Add("prod", credential); err != nil {
+		t.Fatal(err)
+	}
+	if err := vault.Add("prod", credential); !errors.Is(err, ErrCredentialExists) {
+		t.Fatalf("Add existing: %v", err)
+	}
+	if err := vault.Add(vaultCheckName, credential); err == nil {
+		t.Fatalf("Add %q succeeded", vaultCheckName)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("secret-1")) || bytes.Contains(data, []byte("LTAI0000000000000001")) {
+		t.Fatal("vault file contains the credential in plaintext")
+	}
+
+	reopened, err := OpenVault(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := reopened.Lookup("prod")
+	if !ok || got.AccessKeyID != credential.AccessKeyID || got.AccessKeySecret != credential.AccessKeySecret ||
+		got.SecurityToken != credential.SecurityToken || got.Name != "prod" {
+		t.Fatalf("Lookup = %+v, %v", got, ok)
+	}
+
+	if _, err := OpenVault(path, VaultKey{Passphrase: "wrong"}); !errors.Is(err, ErrVaultKey) {
+		t.Fatalf("wrong passphrase: %v", err)
+	}
+}
+
+func TestVaultRejectsTampering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.json")
+	key := VaultKey{Passphrase: "correct horse"}
+	vault, err := OpenVault(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"alpha", "beta"} {
+		if err := vault.Add(name, Credential{AccessKeyID: "LTAI-" + name, AccessKeySecret: "secret-" + name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	original := readTestVaultFile(t, path)
+
+	// 密文被修改
+	tampered := readTestVaultFile(t, path)
+	entry := tampered.Entries["alpha"]
+	entry.Data = append([]byte(nil), entry.Data...)
+	entry.Data[len(entry.Data)-1] ^= 1
+	tampered.Entries["alpha"] = entry
+	writeTestVaultFile(t, path, tampered)
+	if _, err := OpenVault(path, key); !errors.Is(err, ErrVaultKey) {
+		t.Fatalf("tampered ciphertext: %v", err)
+	}
+
+	// 密文挪到其他名称下，附加数据不匹配
+	swapped := readTestVaultFile(t, path)
+	swapped.Entries["alpha"], swapped.Entries["beta"] = original.Entries["beta"], original.Entries["alpha"]
+	writeTestVaultFile(t, path, swapped)
+	if _, err := OpenVault(path, key); !errors.Is(err, ErrVaultKey) {
+		t.Fatalf("swapped names: %v", err)
+	}
+
+	// 凭证密文不能冒充校验块
+	forged := original
+	forged.Check = original.Entries["alpha"].Data
+	writeTestVaultFile(t, path, forged)
+	if _, err := OpenVault(path, key); !errors.Is(err, ErrVaultKey) {
+		t.Fatalf("forged check: %v", err)
+	}
+}
+
+func TestVaultRekey(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vault.json")
+	oldKey := VaultKey{Passphrase: "old passphrase"}
+	vault, err := OpenVault(path, oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{"alpha", "beta", "gamma"}
+	for _, name := range names {
+		if err := vault.Add(name, Credential{AccessKeyID: "LTAI-" + name, AccessKeySecret: "secret-" + name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before := readTestVaultFile(t, path)
+
+	keyFile := filepath.Join(dir, "vault.key")
+	if err := os.WriteFile(keyFile, bytes.Repeat([]byte{7}, vaultKeyFileMinBytes), 0600); err != nil {
+		t.Fatal(err)
+	}
+	newKey := VaultKey{KeyFile: keyFile}
+	if err := vault.Rekey(newKey); err != nil {
+		t.Fatal(err)
+	}
+
+	after := readTestVaultFile(t, path)
+	if after.KDF != vaultKDFKeyFile || bytes.Equal(after.Check, before.Check) {
+		t.Fatalf("kdf = %q, check re-encrypted = %v", after.KDF, !bytes.Equal(after.Check, before.Check))
+	}
+	for _, name := range names {
+		if bytes.Equal(after.Entries[name].Data, before.Entries[name].Data) {
+			t.Errorf("%s was not re-encrypted", name)
+		}
+		if !after.Entries[name].CreatedAt.Equal(before.Entries[name].CreatedAt) {
+			t.Errorf("%s created_at changed", name)
+		}
+	}
+
+	if _, err := OpenVault(path, oldKey); err == nil {
+		t.Fatal("old passphrase still opens the vault")
+	}
+	reopened, err := OpenVault(path, newKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		if got, ok := reopened.Lookup(name); !ok || got.AccessKeySecret != "secret-"+name {
+			t.Errorf("Lookup(%s) = %+v, %v", name, got, ok)
+		}
+	}
+
+	// 换钥后在同一个实例上继续修改，使用的是新密钥
+	if err := vault.Rotate("alpha", Credential{AccessKeyID: "LTAI-alpha2", AccessKeySecret: "secret-alpha2"}); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err = OpenVault(path, newKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := reopened.Lookup("alpha"); got.AccessKeySecret != "secret-alpha2" {
+		t.Fatalf("Lookup(alpha) after rotate = %+v", got)
+	}
+}
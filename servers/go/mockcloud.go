@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/firadio/jinja-hub/hub/mockcloud"
+)
+
+const mockCloudUsage = `用法: jinja-hub mock-cloud [选项]
+
+启动模拟的阿里云 ECS / VPC OpenAPI，用于没有真实账号时开发和测试阿里云站点。
+请求按阿里云的规则校验签名，资源从夹具加载后保存在内存中，修改在重启或 POST /_mock/reset 后还原，
+GET /_mock/state 返回当前的全部资源。
+
+把站点 config.json 中 api.ecs.endpoint 和 api.vpc.endpoint 改为模拟服务的地址（例如 http://localhost:18081/），
+在登录页使用模拟凭证（-access-key-id / -access-key-secret）登录；也可以把模拟凭证写入服务端凭证文件，通过签名代理访问。
+
+选项:
+`
+
+// runMockCloud 处理 mock-cloud 子命令，返回进程退出码
+func runMockCloud(args []string) int {
+	flags := flag.NewFlagSet("mock-cloud", flag.ContinueOnError)
+	addr := flags.String("addr", ":18081", "监听地址")
+	fixtures := flags.String("fixtures", "", "夹具目录 (regions.json、instances.json、vpcs.json、vswitches.json、eips.json、disks.json)，为空时使用内置夹具")
+	accessKeyID := flags.String("access-key-id", mockcloud.DefaultAccessKeyID, "接受的 AccessKey ID")
+	accessKeySecret := flags.String("access-key-secret", mockcloud.DefaultAccessKeySecret, "接受的 AccessKey Secret")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), mockCloudUsage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return 2
+	}
+
+	opts := mockcloud.Options{
+		AccessKeyID:     *accessKeyID,
+		AccessKeySecret: *accessKeySecret,
+		Logger:          log.Default(),
+	}
+	if *fixtures != "" {
+		opts.Fixtures = os.DirFS(*fixtures)
+	}
+	srv, err := mockcloud.New(opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load fixtures:", err)
+		return 1
+	}
+
+	log.Printf("Mock Aliyun OpenAPI listening on %s", *addr)
+	log.Printf("AccessKey ID: %s, AccessKey Secret: %s", *accessKeyID, *accessKeySecret)
+	if err := http.ListenAndServe(*addr, srv); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/firadio/jinja-hub/hub"
+	"golang.org/x/term"
+)
+
+const vaultUsage = `用法: jinja-hub vault [选项] <命令> [名称]
+
+管理加密保存的云服务凭证（AES-256-GCM），签名代理通过名称引用其中的凭证。
+
+命令:
+  add <名称>      添加凭证，依次输入 AccessKey ID、AccessKey Secret 和可选的 STS 令牌
+  list            列出凭证名称、打码的 AccessKey ID 和时间
+  rotate <名称>   替换已有凭证（轮换 AccessKey）
+  remove <名称>   删除凭证
+  rekey           更换口令或密钥文件，用新密钥重新加密所有凭证（新密钥文件由 -new-key-file 指定，否则输入新口令）
+
+口令从环境变量 JINJA_HUB_VAULT_PASSPHRASE 读取，未设置时在终端中输入；配置了密钥文件时使用密钥文件。
+凭证从终端输入时不回显，也可以从标准输入按行传入。修改后向服务发送 SIGHUP 或调用 /_admin/reload 生效。
+
+选项:
+`
+
+// runVault 处理 vault 子命令，返回进程退出码
+func runVault(args []string) int {
+	flags := flag.NewFlagSet("vault", flag.ContinueOnError)
+	configPath := flags.String("config", "", "服务器配置文件路径 (JSON)，从中读取 vault.file 和 vault.key_file")
+	file := flags.String("file", "", "保险库文件路径")
+	keyFile := flags.String("key-file", "", "密钥文件路径 (至少 32 字节的随机内容)")
+	newKeyFile := flags.String("new-key-file", "", "rekey 使用的新密钥文件路径，为空时输入新口令")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), vaultUsage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg := defaultServerConfig().Vault
+	if *configPath != "" {
+		loaded, err := loadServerConfig(*configPath, defaultServerConfig())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to load server config:", err)
+			return 1
+		}
+		cfg = loaded.Vault
+	}
+	if *file != "" {
+		cfg.File = *file
+	}
+	if *keyFile != "" {
+		cfg.KeyFile = *keyFile
+	}
+
+	command, name := flags.Arg(0), flags.Arg(1)
+	needsName := command == "add" || command == "rotate" || command == "remove"
+	if command == "" || cfg.File == "" || needsName != (name != "") || flags.NArg() > 2 {
+		flags.Usage()
+		return 2
+	}
+
+	in := newPrompter()
+	vault, err := openVaultInteractive(cfg, in)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	switch command {
+	case "list":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tACCESS KEY ID\tCREATED\tUPDATED")
+		for _, entry := range vault.List() {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.Name, entry.AccessKeyID,
+				entry.CreatedAt.Local().Format("2006-01-02 15:04"), entry.UpdatedAt.Local().Format("2006-01-02 15:04"))
+		}
+		w.Flush()
+		return 0
+	case "add", "rotate":
+		credential, err := readCredential(in)
+		if err == nil {
+			if command == "add" {
+				err = vault.Add(name, credential)
+			} else {
+				err = vault.Rotate(name, credential)
+			}
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Credential %s saved (%s)\n", name, credential)
+		return 0
+	case "remove":
+		if err := vault.Remove(name); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Credential %s removed\n", name)
+		return 0
+	case "rekey":
+		key, err := readNewVaultKey(*newKeyFile, in)
+		if err == nil {
+			err = vault.Rekey(key)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Vault re-encrypted with the new key (%d credentials); update vault.key_file or JINJA_HUB_VAULT_PASSPHRASE before reloading\n", len(vault.List()))
+		return 0
+	default:
+		flags.Usage()
+		return 2
+	}
+}
+
+// openVaultInteractive 打开保险库，没有密钥文件和口令环境变量时在终端中输入口令；
+// 创建新的保险库时口令需要输入两次
+func openVaultInteractive(cfg vaultConfig, in *prompter) (*hub.Vault, error) {
+	key, err := cfg.key()
+	if err != nil {
+		passphrase, err := in.read("Vault passphrase: ", true)
+		if err != nil {
+			return nil, err
+		}
+		if passphrase == "" {
+			return nil, errors.New("vault: passphrase must not be empty")
+		}
+		if _, statErr := os.Stat(cfg.File); errors.Is(statErr, os.ErrNotExist) {
+			confirm, err := in.read("Confirm passphrase: ", true)
+			if err != nil {
+				return nil, err
+			}
+			if confirm != passphrase {
+				return nil, errors.New("vault: passphrases do not match")
+			}
+		}
+		key = hub.VaultKey{Passphrase: passphrase}
+	}
+	return hub.OpenVault(cfg.File, key)
+}
+
+// readNewVaultKey rekey 的新密钥：指定了新密钥文件时使用密钥文件，否则输入两次新口令
+func readNewVaultKey(keyFile string, in *prompter) (hub.VaultKey, error) {
+	if keyFile != "" {
+		return hub.VaultKey{KeyFile: keyFile}, nil
+	}
+	passphrase, err := in.read("New vault passphrase: ", true)
+	if err != nil {
+		return hub.VaultKey{}, err
+	}
+	if passphrase == "" {
+		return hub.VaultKey{}, errors.New("vault: passphrase must not be empty")
+	}
+	confirm, err := in.read("Confirm new passphrase: ", true)
+	if err != nil {
+		return hub.VaultKey{}, err
+	}
+	if confirm != passphrase {
+		return hub.VaultKey{}, errors.New("vault: passphrases do not match")
+	}
+	return hub.VaultKey{Passphrase: passphrase}, nil
+}
+
+// readCredential 输入一组凭证
+func readCredential(in *prompter) (hub.Credential, error) {
+	var credential hub.Credential
+	var err error
+	if credential.AccessKeyID, err = in.read("AccessKey ID: ", false); err != nil {
+		return credential, err
+	}
+	if credential.AccessKeySecret, err = in.read("AccessKey Secret: ", true); err != nil {
+		return credential, err
+	}
+	if credential.SecurityToken, err = in.read("Security token (optional): ", true); err != nil && !errors.Is(err, io.EOF) {
+		return credential, err
+	}
+	return credential, nil
+}
+
+// prompter 从终端（提示并且密钥不回显）或标准输入（按行读取）读取输入
+type prompter struct {
+	reader   *bufio.Reader
+	terminal bool
+}
+
+func newPrompter() *prompter {
+	return &prompter{
+		reader:   bufio.NewReader(os.Stdin),
+		terminal: term.IsTerminal(int(os.Stdin.Fd())),
+	}
+}
+
+func (p *prompter) read(label string, secret bool) (string, error) {
+	if p.terminal {
+		fmt.Fprint(os.Stderr, label)
+		if secret {
+			value, err := term.ReadPassword(int(os.Stdin.Fd()))
+			fmt.Fprintln(os.Stderr)
+			return strings.TrimSpace(string(value)), err
+		}
+	}
+	line, err := p.reader.ReadString('\n')
+	if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
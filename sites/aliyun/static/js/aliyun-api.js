@@ -67,7 +67,12 @@ async function generateSignature(params, accessKeySecret) {
 }
 
 // 获取 API 端点
+// 优先使用站点配置 api.{product}.endpoint（例如指向 jinja-hub mock-cloud 模拟服务）
 function getApiEndpoint(action, regionId) {
+    const endpoint = window.APP_CONFIG?.api?.[getApiProduct(action)]?.endpoint;
+    if (endpoint) {
+        return endpoint.replace('{region}', regionId);
+    }
     // ECS API
     if (action.startsWith('Describe') && (action.includes('Instance') || action.includes('Region'))) {
         return `https://ecs.${regionId}.aliyuncs.com/`;
@@ -82,6 +87,10 @@ function getApiEndpoint(action, regionId) {
 
 // 获取 API 版本
 function getApiVersion(action) {
+    const version = window.APP_CONFIG?.api?.[getApiProduct(action)]?.version;
+    if (version) {
+        return version;
+    }
     // VPC API 版本
     if (action.includes('Vpc') || action.includes('VSwitch') || action.includes('Eip') || action === 'DeletionProtection') {
         return '2016-04-28';
@@ -90,8 +99,186 @@ function getApiVersion(action) {
     return '2014-05-26';
 }
 
+// 获取 API 所属产品（对应站点配置 api 中的 ecs / vpc）
+function getApiProduct(action) {
+    if (action.includes('Vpc') || action.includes('VSwitch') || action.includes('Eip') || action === 'DeletionProtection') {
+        return 'vpc';
+    }
+    return 'ecs';
+}
+
+// 服务端是否配置了凭证（启用签名代理时由服务器在 config 中标记 api_proxy）
+function isApiProxyEnabled() {
+    return !!window.APP_CONFIG?.api_proxy;
+}
+
+// 使用服务端凭证时的占位密钥，不包含任何 AccessKey
+var SERVER_PROXY_KEY = {
+    name: '服务端凭证',
+    accessKeyId: '',
+    accessKeySecret: ''
+};
+
+// 使用服务端凭证的接口需要登录：服务端返回 Unauthorized 时提示输入管理员分配的访问令牌，
+// 通过 api/session 换取会话 Cookie 后重试一次；用户取消时返回原来的响应
+async function apiFetch(url, options = {}) {
+    let response = await fetch(url, { credentials: 'same-origin', ...options });
+    if (response.status !== 401) {
+        return response;
+    }
+    const token = prompt('请输入访问令牌（由服务器管理员分配）');
+    if (!token) {
+        return response;
+    }
+    const basePath = window.APP_CONFIG?.base_path || '';
+    const login = await fetch(`${basePath}/api/session`, {
+        method: 'POST',
+        credentials: 'same-origin',
+        body: new URLSearchParams({ token })
+    });
+    if (!login.ok) {
+        return login;
+    }
+    return await fetch(url, { credentials: 'same-origin', ...options });
+}
+
+// 通过服务端签名代理调用 API，AccessKey 只保存在服务器上
+// 服务端策略要求确认的非只读接口返回 ConfirmationRequired，用户确认后带上令牌重新提交；用户取消时返回 Canceled 错误
+async function AliyunApiProxy(requestParams) {
+    const { Action, ...params } = requestParams;
+    const basePath = window.APP_CONFIG?.base_path || '';
+    const url = `${basePath}/api/proxy/${getApiProduct(Action)}/${Action}?${new URLSearchParams(params).toString()}`;
+
+    try {
+        const response = await apiFetch(url);
+        const data = await response.json();
+        if (data.Code === 'ConfirmationRequired') {
+            if (!confirm(`确定要执行 ${Action} 吗？`)) {
+                return { Code: 'Canceled', Message: '操作已取消' };
+            }
+            const confirmed = await apiFetch(`${url}&confirm_token=${encodeURIComponent(data.ConfirmToken)}`);
+            return await confirmed.json();
+        }
+        return data;
+    } catch (error) {
+        console.error('API Error:', error);
+        throw error;
+    }
+}
+
+// 表示“全部地域”的 RegionId，由服务端并发查询各地域后合并结果
+var ALL_REGIONS = 'all';
+
+// 通过服务端跨地域聚合查询调用列表 API，返回合并后的全部结果（不分页）和各地域的 RegionResults
+async function AliyunApiFanOut(requestParams) {
+    const { Action, RegionId, PageNumber, PageSize, ...params } = requestParams;
+    const tables = window.APP_CONFIG?.tables || {};
+    const tableKey = Object.keys(tables).find(key => tables[key].apiFunction === Action);
+    if (!tableKey) {
+        throw new Error(`Table not found for action: ${Action}`);
+    }
+    const basePath = window.APP_CONFIG?.base_path || '';
+    const url = `${basePath}/api/fanout/${tableKey}?${new URLSearchParams(params).toString()}`;
+
+    try {
+        const response = await apiFetch(url);
+        const data = await response.json();
+        return data;
+    } catch (error) {
+        console.error('API Error:', error);
+        throw error;
+    }
+}
+
+// 服务端是否提供表格导出（api/export/），只有 Go 服务端提供
+function isApiExportEnabled() {
+    return !!window.APP_CONFIG?.api_export;
+}
+
+// 把表格的行提交给服务端，按表格配置的列生成 CSV / XLSX / NDJSON 文件，返回 { blob, filename }
+async function ExportTable(tableKey, format, rows) {
+    const basePath = window.APP_CONFIG?.base_path || '';
+    const url = `${basePath}/api/export/${tableKey}?format=${encodeURIComponent(format)}`;
+    const response = await fetch(url, {
+        method: 'POST',
+        credentials: 'same-origin',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(rows)
+    });
+    if (!response.ok) {
+        const data = await response.json().catch(() => ({}));
+        throw new Error(data.Message || `导出失败 (HTTP ${response.status})`);
+    }
+    const disposition = response.headers.get('Content-Disposition') || '';
+    const filename = disposition.match(/filename="([^"]+)"/)?.[1] || `${tableKey}.${format}`;
+    return { blob: await response.blob(), filename };
+}
+
+// 通过服务端推送（Server-Sent Events）关注资源状态，服务端轮询并在所有资源到达最终状态后结束
+// onStatus 在状态变化时调用，参数为 {id, status, done, row}，资源已不存在时为 {id, gone: true}；onEnd 在推送结束时调用
+// 返回 EventSource，调用 close() 可以提前停止；没有启用签名代理或表格没有配置 statusField 时返回 null
+function WatchResourceStatus(tableKey, regionId, ids, onStatus, onEnd) {
+    const table = window.APP_CONFIG?.tables?.[tableKey];
+    if (!isApiProxyEnabled() || !table?.statusField || typeof EventSource === 'undefined') {
+        return null;
+    }
+    const basePath = window.APP_CONFIG?.base_path || '';
+    const params = new URLSearchParams({ region: regionId, ids: ids.join(',') });
+    const source = new EventSource(`${basePath}/api/status/${tableKey}?${params.toString()}`);
+
+    source.addEventListener('status', event => onStatus(JSON.parse(event.data)));
+    source.addEventListener('gone', event => onStatus({ ...JSON.parse(event.data), gone: true }));
+    source.addEventListener('error', event => {
+        // 服务端的 error 事件带有 data；连接断开时浏览器会自动重连
+        if (event.data) {
+            console.error('Status stream error:', JSON.parse(event.data));
+        }
+    });
+    source.addEventListener('end', event => {
+        source.close();
+        onEnd?.(JSON.parse(event.data));
+    });
+    return source;
+}
+
+// 通过服务端执行 resource_manage 中的操作，服务端按策略放行或拒绝并记录审计日志
+// 需要确认的操作第一次提交返回 ConfirmationRequired 和确认令牌，用户确认后带上令牌重新提交；用户取消时返回 null
+async function AliyunManageAction(resourceKey, actionName, regionId, resourceId, value, confirmMessage) {
+    const basePath = window.APP_CONFIG?.base_path || '';
+    const url = `${basePath}/api/manage/${resourceKey}/${actionName}`;
+    const body = new URLSearchParams({ region: regionId, resource_id: resourceId });
+    if (value !== undefined) {
+        body.set('value', value);
+    }
+
+    try {
+        let response = await apiFetch(url, { method: 'POST', body });
+        let data = await response.json();
+        if (data.Code === 'ConfirmationRequired') {
+            if (!confirm(confirmMessage || '确定要执行该操作吗？')) {
+                return null;
+            }
+            body.set('confirm_token', data.ConfirmToken);
+            response = await fetch(url, { method: 'POST', credentials: 'same-origin', body });
+            data = await response.json();
+        }
+        return data;
+    } catch (error) {
+        console.error('API Error:', error);
+        throw error;
+    }
+}
+
 // 调用阿里云 API
+// 没有本地 AccessKey 且服务端启用了签名代理时，改为通过代理调用
 async function AliyunApi(requestParams2, accessKeyId, accessKeySecret) {
+    if (!accessKeyId && isApiProxyEnabled()) {
+        if (requestParams2.RegionId === ALL_REGIONS) {
+            return await AliyunApiFanOut(requestParams2);
+        }
+        return await AliyunApiProxy(requestParams2);
+    }
+
     const requestParams1 = {
         AccessKeyId: accessKeyId,
         Format: 'JSON',
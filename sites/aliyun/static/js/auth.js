@@ -29,7 +29,7 @@ class AccessKeyStore {
     getCurrentKey() {
         const keys = this.getKeys();
         const index = this.getCurrentIndex();
-        return keys[index] || null;
+        return keys[index] || (isApiProxyEnabled() ? SERVER_PROXY_KEY : null);
     }
 
     addKey(key) {
@@ -96,7 +96,7 @@ class AccessKeyStore {
     }
 
     isLoggedIn() {
-        return this.getKeys().length > 0;
+        return this.getKeys().length > 0 || isApiProxyEnabled();
     }
 
     clear() {
@@ -121,7 +121,7 @@ if (document.getElementById('loginForm')) {
         const currentKey = keyStore.getCurrentKey();
 
         infoDiv.innerHTML = `
-            <p>您已登录，当前使用: <strong>${currentKey.name}</strong> (${currentKey.accessKeyId})</p>
+            <p>您已登录，当前使用: <strong>${currentKey.name}</strong>${currentKey.accessKeyId ? ` (${currentKey.accessKeyId})` : ''}</p>
             <p>共有 ${keys.length} 个密钥</p>
             <p><a href="${basePath}/ecs_instances.html" class="btn btn-sm btn-primary">前往 ECS 实例页面</a></p>
         `;
@@ -19,6 +19,14 @@ function createDataTable(tableKey) {
         totalCount: 0,
         regionId: '',
         filters: {},
+        allRegionsItems: null,
+        regionErrors: [],
+        exportEnabled: isApiExportEnabled(),
+
+        // 使用服务端凭证时可以选择“全部地域”，由服务端聚合查询
+        get allRegionsEnabled() {
+            return isApiProxyEnabled() && !!tableConfig.product && !window.appStore.keys.getCurrentKey()?.accessKeyId;
+        },
 
         // 计算属性：筛选字段
         get filterFields() {
@@ -27,7 +35,11 @@ function createDataTable(tableKey) {
 
         // 计算属性：表格字段
         get tableFields() {
-            return tableConfig.fields?.filter(f => f.showInTable) || [];
+            const fields = tableConfig.fields?.filter(f => f.showInTable) || [];
+            if (this.regionId === ALL_REGIONS) {
+                return [{ field: 'RegionId', label: '地域', showInTable: true }, ...fields];
+            }
+            return fields;
         },
 
         // 初始化筛选字段
@@ -62,6 +74,9 @@ function createDataTable(tableKey) {
 
             // 读取地域
             this.regionId = urlParams.get('regionId') || window.appStore.keys.getDefaultRegion() || 'cn-hangzhou';
+            if (this.regionId === ALL_REGIONS && !this.allRegionsEnabled) {
+                this.regionId = window.appStore.keys.getDefaultRegion() || 'cn-hangzhou';
+            }
 
             // 读取分页参数
             const page = urlParams.get('page');
@@ -143,6 +158,12 @@ function createDataTable(tableKey) {
                     return;
                 }
 
+                // 全部地域：服务端一次返回所有地域的全部结果，翻页时在本地分页
+                if (this.regionId === ALL_REGIONS && this.allRegionsItems && page > 1) {
+                    this.showAllRegionsPage();
+                    return;
+                }
+
                 const data = await apiFunction(
                     this.regionId,
                     currentKey.accessKeyId,
@@ -164,6 +185,15 @@ function createDataTable(tableKey) {
                     items = items?.[key];
                 }
 
+                if (this.regionId === ALL_REGIONS) {
+                    this.allRegionsItems = items || [];
+                    this.regionErrors = (data.RegionResults || []).filter(r => r.Code || r.Truncated);
+                    this.showAllRegionsPage();
+                    return;
+                }
+
+                this.allRegionsItems = null;
+                this.regionErrors = [];
                 this.dataItems = items || [];
                 this.totalCount = data.TotalCount || 0;
 
@@ -182,12 +212,36 @@ function createDataTable(tableKey) {
             }
         },
 
+        // 从全部地域的结果中取出当前页
+        showAllRegionsPage() {
+            const start = (this.currentPage - 1) * this.pageSize;
+            this.dataItems = this.allRegionsItems.slice(start, start + Number(this.pageSize));
+            this.totalCount = this.allRegionsItems.length;
+        },
+
+        // 导出已经加载的数据：全部地域时为所有地域的全部结果，否则为当前页
+        async exportData(format) {
+            const rows = this.allRegionsItems || this.dataItems;
+            try {
+                const { blob, filename } = await ExportTable(tableKey, format, rows);
+                const link = document.createElement('a');
+                link.href = URL.createObjectURL(blob);
+                link.download = filename;
+                link.click();
+                URL.revokeObjectURL(link.href);
+            } catch (error) {
+                this.error = error.message || '导出失败';
+            }
+        },
+
         async search() {
             await this.loadData(1);
         },
 
         async changeRegion() {
-            window.appStore.keys.setDefaultRegion(this.regionId);
+            if (this.regionId !== ALL_REGIONS) {
+                window.appStore.keys.setDefaultRegion(this.regionId);
+            }
             await this.loadData(1);
         },
 
@@ -28,8 +28,8 @@ function regionMixin() {
 
                 this.regions = regions;
 
-                // 确保regionId有效
-                if (this.regions.length > 0) {
+                // 确保regionId有效（全部地域除外）
+                if (this.regions.length > 0 && this.regionId !== ALL_REGIONS) {
                     if (!this.regionId || !this.regions.find(r => r.id === this.regionId)) {
                         this.regionId = this.regions[0].id;
                     }
@@ -14,6 +14,7 @@ function createResourceManage(resourceKey) {
         loading: true,
         error: '',
         operating: false,
+        statusSource: null,
         showRenameForm: false,
         isEmbedMode: false,
         renameForm: {
@@ -56,6 +57,9 @@ function createResourceManage(resourceKey) {
             }
 
             await this.loadResource();
+
+            // 资源处于中间状态（如启动中）时关注状态变化
+            this.watchStatus(true);
         },
 
         async loadResource() {
@@ -124,6 +128,32 @@ function createResourceManage(resourceKey) {
             }
         },
 
+        // 使用服务端凭证时，通过服务端推送实时更新资源状态，直到到达最终状态
+        // onlyTransitional 为 true 时只在资源处于中间状态（不在表格的 terminalStatus 中）时关注
+        watchStatus(onlyTransitional) {
+            const currentKey = window.appStore.keys.getCurrentKey();
+            if (currentKey?.accessKeyId || !this.resource) return;
+
+            const tables = window.APP_CONFIG?.tables || {};
+            const tableKey = Object.keys(tables).find(key => tables[key].apiFunction === manageConfig.apiGetFunction);
+            const table = tables[tableKey];
+            if (!table?.statusField) return;
+            const status = this.getFieldValue(this.resource, {field: table.statusField});
+            if (onlyTransitional && (!table.terminalStatus || table.terminalStatus.includes(status))) return;
+
+            this.statusSource?.close();
+            this.statusSource = WatchResourceStatus(tableKey, this.regionId, [this.resourceId], event => {
+                if (event.gone) {
+                    this.resource = null;
+                    this.error = '资源不存在';
+                } else {
+                    this.resource = event.row;
+                }
+            }, () => {
+                this.statusSource = null;
+            });
+        },
+
         // 获取字段值
         getFieldValue(resource, field) {
             const fieldPath = field.field.replace(/\[(\d+)\]/g, '.$1').split('.');
@@ -187,6 +217,12 @@ function createResourceManage(resourceKey) {
             return new Date(timeStr).toLocaleString('zh-CN');
         },
 
+        // 使用服务端凭证时，配置了 api 的操作由服务端按策略执行（确认由服务端要求）
+        isServerManaged(action) {
+            const currentKey = window.appStore.keys.getCurrentKey();
+            return !currentKey?.accessKeyId && isApiProxyEnabled() && !!action?.api;
+        },
+
         // 处理操作按钮点击
         async handleAction(action) {
             if (action.type === 'form') {
@@ -206,7 +242,7 @@ function createResourceManage(resourceKey) {
             }
 
             // API 操作
-            if (action.confirmMessage && !confirm(action.confirmMessage)) {
+            if (action.confirmMessage && !this.isServerManaged(action) && !confirm(action.confirmMessage)) {
                 return;
             }
 
@@ -220,19 +256,25 @@ function createResourceManage(resourceKey) {
 
             try {
                 const currentKey = window.appStore.keys.getCurrentKey();
-                const apiFunction = window[action.apiFunction];
+                let result;
+                if (this.isServerManaged(action)) {
+                    result = await AliyunManageAction(resourceKey, action.name, this.regionId, this.resourceId, undefined, action.confirmMessage);
+                    if (!result) return;
+                } else {
+                    const apiFunction = window[action.apiFunction];
 
-                if (!apiFunction) {
-                    alert(`API function not found: ${action.apiFunction}`);
-                    return;
-                }
+                    if (!apiFunction) {
+                        alert(`API function not found: ${action.apiFunction}`);
+                        return;
+                    }
 
-                const result = await apiFunction(
-                    this.regionId,
-                    this.resourceId,
-                    currentKey.accessKeyId,
-                    currentKey.accessKeySecret
-                );
+                    result = await apiFunction(
+                        this.regionId,
+                        this.resourceId,
+                        currentKey.accessKeyId,
+                        currentKey.accessKeySecret
+                    );
+                }
 
                 if (result.Code) {
                     alert(`操作失败：${result.Message}`);
@@ -263,6 +305,7 @@ function createResourceManage(resourceKey) {
                     }
 
                     await this.loadResource();
+                    this.watchStatus(false);
                 }
             } catch (error) {
                 alert(`操作失败：${error.message}`);
@@ -280,20 +323,27 @@ function createResourceManage(resourceKey) {
 
             try {
                 const currentKey = window.appStore.keys.getCurrentKey();
-                const apiFunction = window[manageConfig.renameConfig.apiFunction];
+                const renameAction = manageConfig.actions.find(action => action.name === 'rename');
+                let result;
+                if (this.isServerManaged(renameAction)) {
+                    result = await AliyunManageAction(resourceKey, 'rename', this.regionId, this.resourceId, this.renameForm.name);
+                    if (!result) return;
+                } else {
+                    const apiFunction = window[manageConfig.renameConfig.apiFunction];
 
-                if (!apiFunction) {
-                    alert(`API function not found: ${manageConfig.renameConfig.apiFunction}`);
-                    return;
-                }
+                    if (!apiFunction) {
+                        alert(`API function not found: ${manageConfig.renameConfig.apiFunction}`);
+                        return;
+                    }
 
-                const result = await apiFunction(
-                    this.regionId,
-                    this.resourceId,
-                    this.renameForm.name,
-                    currentKey.accessKeyId,
-                    currentKey.accessKeySecret
-                );
+                    result = await apiFunction(
+                        this.regionId,
+                        this.resourceId,
+                        this.renameForm.name,
+                        currentKey.accessKeyId,
+                        currentKey.accessKeySecret
+                    );
+                }
 
                 if (result.Code) {
                     alert(`修改失败：${result.Message}`);
@@ -317,6 +317,10 @@ class ErrorHandler {
             return 'API 调用频率超限,请稍后重试';
         }
 
+        if (message.includes('Too Many Requests') || message.includes('Bandwidth Limit Exceeded')) {
+            return '请求过于频繁,请稍后重试';
+        }
+
         // 通用错误消息截断
         if (message.length > 200) {
             return message.substring(0, 200) + '...';
@@ -474,8 +478,8 @@ class ErrorHandler {
                         message.innerHTML = `<span class="error-loading"></span> 重试中 (${attempt + 1}/${maxRetries})...`;
                     }
 
-                    // 等待后重试
-                    await this.sleep(retryDelay * (attempt + 1));
+                    // 等待后重试（服务器返回 Retry-After 时按其退避）
+                    await this.sleep(this.getRetryDelay(error, retryDelay * (attempt + 1)));
                 } else {
                     // 最后一次尝试也失败了
                     if (loadingToast) {
@@ -496,6 +500,24 @@ class ErrorHandler {
         throw lastError;
     }
 
+    /**
+     * 获取重试等待时间
+     * 错误带有 retryAfter（秒）或 429/503 响应带有 Retry-After 头时优先使用
+     */
+    getRetryDelay(error, defaultDelay) {
+        let retryAfter = error && error.retryAfter;
+        const response = error && error.response;
+        if (retryAfter == null && response && response.headers && (response.status === 429 || response.status === 503)) {
+            retryAfter = response.headers.get('Retry-After');
+        }
+
+        const seconds = Number(retryAfter);
+        if (retryAfter != null && retryAfter !== '' && Number.isFinite(seconds) && seconds >= 0) {
+            return Math.max(seconds * 1000, defaultDelay);
+        }
+        return defaultDelay;
+    }
+
     /**
      * 延迟函数
      */
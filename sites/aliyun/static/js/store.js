@@ -236,13 +236,12 @@ class KeyManager {
         this.storage.set(this.CURRENT_KEY_KEY, id);
     }
 
-    // 获取当前密钥
+    // 获取当前密钥，没有本地密钥且服务端启用了签名代理时返回服务端凭证的占位密钥
     getCurrentKey() {
         const id = this.getCurrentKeyId();
-        if (id === null) return null;
-
         const keys = this.getKeys();
-        return keys[id] || null;
+        const key = id === null ? null : keys[id];
+        return key || (isApiProxyEnabled() ? SERVER_PROXY_KEY : null);
     }
 
     // 清除所有密钥